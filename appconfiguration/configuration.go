@@ -3,15 +3,30 @@ package appconfiguration
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
 // Configuration structure that hold app configuration parameter
 type Configuration struct {
-	LogDebug       bool
-	ServerPort     string
-	BinaryFilePath string
+	LogDebug          bool
+	ServerPort        string
+	BinaryFilePath    string
+	ReuseDeletedSlots bool
+	SyncPolicy        string
+	SyncInterval      time.Duration
+	AutoMigrate       bool
+	MaxStrLength      int
+	MaxSortedRecords  int
+	IndexedFields     []string
+	FullTextSearch    bool
+	RecordHistory     bool
+	DeletedRetention  time.Duration
+	IdempotencyKeyTTL time.Duration
+	SnapshotDir       string
+	RestoreSnapshot   string
 }
 
 // NewAppConfiguration constructor for create object configuration
@@ -43,5 +58,100 @@ func NewAppConfiguration() *Configuration {
 		config.BinaryFilePath = "./records.bin"
 	}
 
+	reuseDeletedSlots, err := strconv.ParseBool(os.Getenv("REUSE_DELETED_SLOTS"))
+
+	if err != nil {
+		reuseDeletedSlots = false
+	}
+
+	config.ReuseDeletedSlots = reuseDeletedSlots
+
+	config.SyncPolicy = os.Getenv("SYNC_POLICY")
+
+	if config.SyncPolicy == "" {
+		config.SyncPolicy = "always"
+	}
+
+	syncInterval, err := time.ParseDuration(os.Getenv("SYNC_INTERVAL"))
+
+	if err != nil {
+		syncInterval = time.Second
+	}
+
+	config.SyncInterval = syncInterval
+
+	autoMigrate, err := strconv.ParseBool(os.Getenv("AUTO_MIGRATE"))
+
+	if err != nil {
+		autoMigrate = true
+	}
+
+	config.AutoMigrate = autoMigrate
+
+	maxStrLength, err := strconv.Atoi(os.Getenv("MAX_STR_LENGTH"))
+
+	if err != nil || maxStrLength <= 0 {
+		maxStrLength = 4096
+	}
+
+	config.MaxStrLength = maxStrLength
+
+	maxSortedRecords, err := strconv.Atoi(os.Getenv("MAX_SORTED_RECORDS"))
+
+	if err != nil || maxSortedRecords <= 0 {
+		maxSortedRecords = 10000
+	}
+
+	config.MaxSortedRecords = maxSortedRecords
+
+	// comma separated list of fields, secondary indexes are disabled by default
+	for _, field := range strings.Split(os.Getenv("INDEXED_FIELDS"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			config.IndexedFields = append(config.IndexedFields, field)
+		}
+	}
+
+	fullTextSearch, err := strconv.ParseBool(os.Getenv("FULL_TEXT_SEARCH"))
+
+	if err != nil {
+		fullTextSearch = false
+	}
+
+	config.FullTextSearch = fullTextSearch
+
+	recordHistory, err := strconv.ParseBool(os.Getenv("RECORD_HISTORY"))
+
+	if err != nil {
+		recordHistory = false
+	}
+
+	config.RecordHistory = recordHistory
+
+	deletedRetention, err := time.ParseDuration(os.Getenv("DELETED_RETENTION"))
+
+	if err != nil || deletedRetention < 0 {
+		deletedRetention = 0
+	}
+
+	config.DeletedRetention = deletedRetention
+
+	// zero time to live disables idempotency keys
+	idempotencyKeyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"))
+
+	if err != nil || idempotencyKeyTTL < 0 {
+		idempotencyKeyTTL = 24 * time.Hour
+	}
+
+	config.IdempotencyKeyTTL = idempotencyKeyTTL
+
+	config.SnapshotDir = os.Getenv("SNAPSHOT_DIR")
+
+	if config.SnapshotDir == "" {
+		config.SnapshotDir = "./snapshots"
+	}
+
+	// storage is restored from snapshot on startup only when storage file does not exist
+	config.RestoreSnapshot = os.Getenv("RESTORE_SNAPSHOT")
+
 	return config
 }
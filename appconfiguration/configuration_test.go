@@ -3,6 +3,7 @@ package appconfiguration
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,6 +14,19 @@ func TestDefaultConfiguration(t *testing.T) {
 	assert.Equal(t, "8080", configWithDefaultValue.ServerPort)
 	assert.Equal(t, false, configWithDefaultValue.LogDebug)
 	assert.Equal(t, "./records.bin", configWithDefaultValue.BinaryFilePath)
+	assert.Equal(t, false, configWithDefaultValue.ReuseDeletedSlots)
+	assert.Equal(t, "always", configWithDefaultValue.SyncPolicy)
+	assert.Equal(t, time.Second, configWithDefaultValue.SyncInterval)
+	assert.Equal(t, true, configWithDefaultValue.AutoMigrate)
+	assert.Equal(t, 4096, configWithDefaultValue.MaxStrLength)
+	assert.Equal(t, 10000, configWithDefaultValue.MaxSortedRecords)
+	assert.Empty(t, configWithDefaultValue.IndexedFields)
+	assert.Equal(t, false, configWithDefaultValue.FullTextSearch)
+	assert.Equal(t, false, configWithDefaultValue.RecordHistory)
+	assert.Equal(t, time.Duration(0), configWithDefaultValue.DeletedRetention)
+	assert.Equal(t, 24*time.Hour, configWithDefaultValue.IdempotencyKeyTTL)
+	assert.Equal(t, "./snapshots", configWithDefaultValue.SnapshotDir)
+	assert.Empty(t, configWithDefaultValue.RestoreSnapshot)
 }
 
 func TestCustomConfiguration(t *testing.T) {
@@ -31,9 +45,87 @@ func TestCustomConfiguration(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	err = os.Setenv("REUSE_DELETED_SLOTS", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("SYNC_POLICY", "interval")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("SYNC_INTERVAL", "250ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("AUTO_MIGRATE", "false")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("MAX_STR_LENGTH", "1024")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("MAX_SORTED_RECORDS", "500")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("INDEXED_FIELDS", "IntValue, TimeValue")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("FULL_TEXT_SEARCH", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("RECORD_HISTORY", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("DELETED_RETENTION", "24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("IDEMPOTENCY_KEY_TTL", "1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("SNAPSHOT_DIR", "/opt/snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Setenv("RESTORE_SNAPSHOT", "/opt/snapshots/snapshot-20231231T124259.987Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	config := NewAppConfiguration()
 
 	assert.Equal(t, "9090", config.ServerPort)
 	assert.Equal(t, true, config.LogDebug)
 	assert.Equal(t, "/opt/records.bin", config.BinaryFilePath)
+	assert.Equal(t, true, config.ReuseDeletedSlots)
+	assert.Equal(t, "interval", config.SyncPolicy)
+	assert.Equal(t, 250*time.Millisecond, config.SyncInterval)
+	assert.Equal(t, false, config.AutoMigrate)
+	assert.Equal(t, 1024, config.MaxStrLength)
+	assert.Equal(t, 500, config.MaxSortedRecords)
+	assert.Equal(t, []string{"IntValue", "TimeValue"}, config.IndexedFields)
+	assert.Equal(t, true, config.FullTextSearch)
+	assert.Equal(t, true, config.RecordHistory)
+	assert.Equal(t, 24*time.Hour, config.DeletedRetention)
+	assert.Equal(t, time.Hour, config.IdempotencyKeyTTL)
+	assert.Equal(t, "/opt/snapshots", config.SnapshotDir)
+	assert.Equal(t, "/opt/snapshots/snapshot-20231231T124259.987Z", config.RestoreSnapshot)
 }
@@ -0,0 +1,62 @@
+package batchrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// batchResponse structure of response with results of all operations, error text is set when batch is not applied
+type batchResponse struct {
+	ErrText string   `json:"errText,omitempty"`
+	Results []result `json:"results"`
+}
+
+// MakePostBatchEndpoint function create POST endpoint for batch of create, update and delete operations
+// 200 is returned when all operations are applied, otherwise status of the failed operation with results of all operations
+func MakePostBatchEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		var operations []operation
+		decoder := json.NewDecoder(request.Body)
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&operations); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		results, err := service.Apply(operations)
+
+		if err != nil && results == nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		statusCode := http.StatusOK
+		batchRes := batchResponse{Results: results}
+
+		var batchError *record.BatchError
+
+		if errors.As(err, &batchError) {
+			statusCode = results[batchError.Index].Status
+			batchRes.ErrText = err.Error()
+		} else if err != nil {
+			statusCode = http.StatusBadRequest
+			batchRes.ErrText = err.Error()
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+		response.WriteHeader(statusCode)
+
+		if err := json.NewEncoder(response).Encode(batchRes); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Batch of %d operations finished with status %d", len(operations), statusCode)
+	}
+}
@@ -0,0 +1,128 @@
+package batchrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRecords(t *testing.T) {
+	const storageFilePath = "/tmp/batch_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedCode     int
+		expectedStatuses []int
+	}{{
+		name:         "Batch which is not array - expected status code 400",
+		body:         `{"op":"create"}`,
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Empty batch - expected status code 400",
+		body:         `[]`,
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name: "Batch with invalid operations - expected status code 400",
+		body: `[{"op":"create","record":{"IntValue":3,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}},
+			{"op":"update","record":{"IntValue":4}},
+			{"op":"replace","id":1}]`,
+		expectedCode:     http.StatusBadRequest,
+		expectedStatuses: []int{http.StatusFailedDependency, http.StatusBadRequest, http.StatusBadRequest},
+	}, {
+		name: "Batch with update of not existing record - expected status code 404",
+		body: `[{"op":"create","record":{"IntValue":3,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}},
+			{"op":"update","id":99,"record":{"IntValue":4,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}}]`,
+		expectedCode:     http.StatusNotFound,
+		expectedStatuses: []int{http.StatusFailedDependency, http.StatusNotFound},
+	}, {
+		name: "Batch with failed If-Match - expected status code 412",
+		body: `[{"op":"delete","id":2},
+			{"op":"update","id":1,"ifMatch":"\"7\"","record":{"IntValue":4,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}}]`,
+		expectedCode:     http.StatusPreconditionFailed,
+		expectedStatuses: []int{http.StatusFailedDependency, http.StatusPreconditionFailed},
+	}, {
+		name: "Valid batch - expected status code 200",
+		body: `[{"op":"create","record":{"IntValue":3,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}},
+			{"op":"update","id":1,"ifMatch":"\"1\"","record":{"IntValue":4,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}},
+			{"op":"delete","id":2}]`,
+		expectedCode:     http.StatusOK,
+		expectedStatuses: []int{http.StatusCreated, http.StatusOK, http.StatusNoContent},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records:batch", MakePostBatchEndpoint(service)).Methods(http.MethodPost)
+
+			req, _ := http.NewRequest("POST", "/records:batch", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedStatuses == nil {
+				return
+			}
+
+			var batchRes batchResponse
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&batchRes))
+
+			statuses := make([]int, 0, len(batchRes.Results))
+
+			for _, result := range batchRes.Results {
+				statuses = append(statuses, result.Status)
+			}
+
+			assert.Equal(t, tt.expectedStatuses, statuses)
+		})
+	}
+
+	// only the valid batch is applied
+	rec, err := fileStorageService.GetRecord(3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), rec.IntValue)
+
+	rec, err = fileStorageService.GetRecord(4)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+
+	rec, err = fileStorageService.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), rec.IntValue)
+	assert.Equal(t, int64(2), rec.Version)
+
+	rec, err = fileStorageService.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+}
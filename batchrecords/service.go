@@ -0,0 +1,148 @@
+package batchrecords
+
+import (
+	"fmt"
+	"interviewtest/createrecord"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// maxOperations is maximal number of operations in one batch
+const maxOperations = 1000
+
+// ErrInvalidOperations error of batch with invalid operations, errors of operations are in results
+var ErrInvalidOperations = errors.New("batch contains invalid operations")
+
+// Service interface provides method for applying batch of operations in file storage
+type Service interface {
+	Apply(operations []operation) ([]result, error)
+}
+
+type service struct {
+	record record.ModificationStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage
+func NewService(record record.Storage) Service {
+	return &service{record: record}
+}
+
+// operation structure of one operation of batch request
+// id is required by update and delete, create with id creates record with the id
+type operation struct {
+	Op      string         `json:"op"`
+	ID      int64          `json:"id,omitempty"`
+	IfMatch string         `json:"ifMatch,omitempty"`
+	Record  *record.Record `json:"record,omitempty"`
+}
+
+// result structure with result of one operation, status is http status code of operation
+// operation not applied because other operation failed has status 424
+type result struct {
+	Index  int                  `json:"index"`
+	Op     string               `json:"op"`
+	Status int                  `json:"status"`
+	ID     int64                `json:"id,omitempty"`
+	ETag   string               `json:"etag,omitempty"`
+	Error  *tools.ErrorResponse `json:"error,omitempty"`
+}
+
+// Apply method validates all operations and applies them in one storage transaction
+// no operation is applied when any operation is invalid or fails, results of all operations are returned
+func (service *service) Apply(operations []operation) ([]result, error) {
+	if len(operations) == 0 {
+		return nil, tools.FieldErrors{{Field: "operations", Message: "batch has no operations"}}
+	}
+
+	if len(operations) > maxOperations {
+		return nil, tools.FieldErrors{{Field: "operations", Message: fmt.Sprintf("batch has more than %d operations", maxOperations)}}
+	}
+
+	results := make([]result, len(operations))
+	valid := true
+
+	for i, op := range operations {
+		results[i] = result{Index: i, Op: op.Op, ID: op.ID, Status: http.StatusFailedDependency}
+
+		if fieldErrors := validateOperation(op); fieldErrors != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = &tools.ErrorResponse{ErrText: fieldErrors.Error(), Fields: fieldErrors}
+			valid = false
+		}
+	}
+
+	if !valid {
+		return results, errors.WithStack(ErrInvalidOperations)
+	}
+
+	storageOperations := make([]record.Operation, len(operations))
+
+	for i, op := range operations {
+		storageOperations[i] = record.Operation{Type: op.Op, ID: op.ID, Record: op.Record}
+
+		if ifMatch := op.IfMatch; ifMatch != "" {
+			storageOperations[i].Condition = func(version int64) error {
+				return tools.CheckIfMatch(ifMatch, version)
+			}
+		}
+	}
+
+	err := service.record.ApplyBatch(storageOperations)
+
+	var batchError *record.BatchError
+
+	if errors.As(err, &batchError) {
+		results[batchError.Index].Status = tools.ErrStatusCode(batchError.Err)
+		results[batchError.Index].Error = &tools.ErrorResponse{ErrText: batchError.Err.Error()}
+
+		return results, err
+	}
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for i, op := range storageOperations {
+		switch op.Type {
+		case record.OperationCreate:
+			results[i].Status = http.StatusCreated
+		case record.OperationUpdate:
+			results[i].Status = http.StatusOK
+		case record.OperationDelete:
+			results[i].Status = http.StatusNoContent
+			continue
+		}
+
+		results[i].ID = op.Record.Id
+		results[i].ETag = tools.ETag(op.Record.Version)
+	}
+
+	return results, nil
+}
+
+// validateOperation function validates operation and its record with validator of created records
+func validateOperation(op operation) tools.FieldErrors {
+	var fieldErrors tools.FieldErrors
+
+	switch op.Op {
+	case record.OperationCreate, record.OperationUpdate:
+		if op.Record == nil {
+			fieldErrors = append(fieldErrors, tools.FieldError{Field: "record", Message: "record is required"})
+		} else if err := createrecord.Validate(op.Record); err != nil {
+			fieldErrors = append(fieldErrors, tools.FieldErrorsFromValidation(err, "record.")...)
+		}
+	case record.OperationDelete:
+	default:
+		return tools.FieldErrors{{Field: "op", Message: "op must be create, update or delete"}}
+	}
+
+	if op.ID < 0 || (op.ID == 0 && op.Op != record.OperationCreate) {
+		fieldErrors = append(fieldErrors, tools.FieldError{Field: "id", Message: "id must be positive"})
+	}
+
+	return fieldErrors
+}
@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"interviewtest/appconfiguration"
+	"interviewtest/batchrecords"
+	"interviewtest/compactrecords"
 	"interviewtest/createrecord"
 	"interviewtest/deleterecord"
 	"interviewtest/editrecord"
+	"interviewtest/exportrecords"
 	"interviewtest/getrecord"
+	"interviewtest/getstats"
 	"interviewtest/healthcheck"
+	"interviewtest/importrecords"
+	"interviewtest/listdeletedrecords"
+	"interviewtest/listrecords"
+	"interviewtest/patchrecord"
+	"interviewtest/recordhistory"
+	"interviewtest/restorerecord"
+	"interviewtest/searchrecords"
+	"interviewtest/snapshotrecords"
 	"interviewtest/storage"
 	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -22,7 +36,16 @@ import (
 func main() {
 	appConf := appconfiguration.NewAppConfiguration()
 
-	storageService, err := storage.NewService(appConf.BinaryFilePath)
+	if len(os.Args) > 1 {
+		runCommand(appConf, os.Args[1:])
+		return
+	}
+
+	if err := restoreSnapshot(appConf); err != nil {
+		log.Fatal(err)
+	}
+
+	storageService, err := openStorage(appConf)
 
 	if err != nil {
 		log.Fatal(err)
@@ -31,16 +54,40 @@ func main() {
 	defer storageService.Close()
 
 	getRecordService := getrecord.NewService(storageService)
+	listRecordsService := listrecords.NewService(storageService, appConf.MaxSortedRecords)
+	searchRecordsService := searchrecords.NewService(storageService)
 	createRecordService := createrecord.NewService(storageService)
 	deleteRecordService := deleterecord.NewService(storageService)
 	putRecordService := editrecord.NewService(storageService)
+	patchRecordService := patchrecord.NewService(storageService)
+	compactRecordsService := compactrecords.NewService(storageService)
+	getStatsService := getstats.NewService(storageService)
+	recordHistoryService := recordhistory.NewService(storageService)
+	restoreRecordService := restorerecord.NewService(storageService)
+	listDeletedRecordsService := listdeletedrecords.NewService(storageService)
+	batchRecordsService := batchrecords.NewService(storageService)
+	exportRecordsService := exportrecords.NewService(storageService)
+	importRecordsService := importrecords.NewService(storageService, appConf.MaxStrLength)
+	snapshotRecordsService := snapshotrecords.NewService(storageService, appConf.SnapshotDir)
 
 	myRouter := mux.NewRouter().StrictSlash(true)
 	myRouter.Handle("/readyz", healthcheck.MakeGetReadyEndpoint()).Methods(http.MethodGet)
+	myRouter.Handle("/records", listrecords.MakeGetListRecordsEndpoint(listRecordsService)).Methods(http.MethodGet)
+	myRouter.Handle("/records/search", searchrecords.MakeGetSearchRecordsEndpoint(searchRecordsService)).Methods(http.MethodGet)
+	myRouter.Handle("/records/export", exportrecords.MakeGetExportRecordsEndpoint(exportRecordsService)).Methods(http.MethodGet)
+	myRouter.Handle("/records/deleted", listdeletedrecords.MakeGetDeletedRecordsEndpoint(listDeletedRecordsService)).Methods(http.MethodGet)
 	myRouter.Handle("/records", createrecord.MakePostCreateRecordEndpoint(createRecordService)).Methods(http.MethodPost)
+	myRouter.Handle("/records/import", importrecords.MakePostImportRecordsEndpoint(importRecordsService)).Methods(http.MethodPost)
+	myRouter.Handle("/records:batch", batchrecords.MakePostBatchEndpoint(batchRecordsService)).Methods(http.MethodPost)
 	myRouter.Handle("/records/{id:[0-9]+}", deleterecord.MakeDeleteRecordEndpoint(deleteRecordService)).Methods(http.MethodDelete)
 	myRouter.Handle("/records/{id:[0-9]+}", editrecord.MakePutRecordEndpoint(putRecordService)).Methods(http.MethodPut)
+	myRouter.Handle("/records/{id:[0-9]+}", patchrecord.MakePatchRecordEndpoint(patchRecordService)).Methods(http.MethodPatch)
 	myRouter.Handle("/records/{id:[0-9]+}", getrecord.MakeGetRecordEndpoint(getRecordService)).Methods(http.MethodGet)
+	myRouter.Handle("/records/{id:[0-9]+}/history", recordhistory.MakeGetRecordHistoryEndpoint(recordHistoryService)).Methods(http.MethodGet)
+	myRouter.Handle("/records/{id:[0-9]+}/restore", restorerecord.MakePostRestoreRecordEndpoint(restoreRecordService)).Methods(http.MethodPost)
+	myRouter.Handle("/admin/compact", compactrecords.MakePostCompactEndpoint(compactRecordsService)).Methods(http.MethodPost)
+	myRouter.Handle("/admin/snapshot", snapshotrecords.MakePostSnapshotEndpoint(snapshotRecordsService)).Methods(http.MethodPost)
+	myRouter.Handle("/admin/stats", getstats.MakeGetStatsEndpoint(getStatsService)).Methods(http.MethodGet)
 
 	srv := http.Server{
 		Addr:    fmt.Sprintf(":%s", appConf.ServerPort),
@@ -60,6 +107,45 @@ func main() {
 	log.Println("Server shutdown gracefully")
 }
 
+// openStorage function opens storage file with options given by configuration
+func openStorage(appConf *appconfiguration.Configuration) (storage.Service, error) {
+	syncPolicy, err := storage.ParseSyncPolicy(appConf.SyncPolicy)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.NewService(appConf.BinaryFilePath,
+		storage.WithSlotReuse(appConf.ReuseDeletedSlots),
+		storage.WithSyncPolicy(syncPolicy, appConf.SyncInterval),
+		storage.WithAutoMigrate(appConf.AutoMigrate),
+		storage.WithMaxStrLength(appConf.MaxStrLength),
+		storage.WithIndexes(appConf.IndexedFields...),
+		storage.WithFullTextSearch(appConf.FullTextSearch),
+		storage.WithHistory(appConf.RecordHistory),
+		storage.WithDeletedRetention(appConf.DeletedRetention),
+		storage.WithIdempotencyTTL(appConf.IdempotencyKeyTTL))
+}
+
+// restoreSnapshot function restores storage from snapshot given by configuration before storage is opened
+// existing storage file is never replaced on startup, it is replaced by command restore only
+func restoreSnapshot(appConf *appconfiguration.Configuration) error {
+	if appConf.RestoreSnapshot == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(appConf.BinaryFilePath); err == nil {
+		log.Warnf("Snapshot %s is not restored, storage file %s exists", appConf.RestoreSnapshot, appConf.BinaryFilePath)
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	_, err := storage.RestoreSnapshot(appConf.RestoreSnapshot, appConf.BinaryFilePath)
+
+	return err
+}
+
 func shutDownServer(srv *http.Server) chan struct{} {
 	idleConnectionsClosed := make(chan struct{})
 
@@ -77,8 +163,97 @@ func shutDownServer(srv *http.Server) chan struct{} {
 }
 
 func corsOptions(myRouter *mux.Router) http.Handler {
-	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type"})
-	allowedMethods := handlers.AllowedMethods([]string{http.MethodGet, http.MethodPost, http.MethodOptions, http.MethodPut, http.MethodPost})
+	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "If-None-Match", "If-Match", "Idempotency-Key"})
+	exposedHeaders := handlers.ExposedHeaders([]string{"ETag", "Idempotent-Replayed"})
+	allowedMethods := handlers.AllowedMethods([]string{http.MethodGet, http.MethodPost, http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodPost})
+
+	return handlers.CORS(allowedHeaders, exposedHeaders, allowedMethods)(myRouter)
+}
+
+// runCommand function runs maintenance command instead of http server
+// Commands work with storage file offline, server must not be running
+func runCommand(appConf *appconfiguration.Configuration, args []string) {
+	switch args[0] {
+	case "compact":
+		idMapping, err := storage.CompactFile(appConf.BinaryFilePath, appConf.DeletedRetention, appConf.MaxStrLength)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := json.NewEncoder(os.Stdout).Encode(idMapping); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Infof("Storage file %s compacted, %d records moved", appConf.BinaryFilePath, len(idMapping))
+	case "migrate":
+		// file is migrated in place when target path is not defined
+		targetPath := appConf.BinaryFilePath
 
-	return handlers.CORS(allowedHeaders, allowedMethods)(myRouter)
+		if len(args) > 1 {
+			targetPath = args[1]
+		}
+
+		if err := storage.MigrateFile(appConf.BinaryFilePath, targetPath); err != nil {
+			log.Fatal(err)
+		}
+	case "reindex":
+		if err := storage.RebuildIndexFiles(appConf.BinaryFilePath,
+			storage.WithIndexes(appConf.IndexedFields...),
+			storage.WithFullTextSearch(appConf.FullTextSearch)); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Infof("Indexes %v of storage file %s rebuilt", appConf.IndexedFields, appConf.BinaryFilePath)
+	case "check":
+		issues, err := storage.CheckFile(appConf.BinaryFilePath)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := json.NewEncoder(os.Stdout).Encode(issues); err != nil {
+			log.Fatal(err)
+		}
+
+		if len(issues) > 0 {
+			log.Fatalf("Storage file %s has %d damaged record slots", appConf.BinaryFilePath, len(issues))
+		}
+
+		log.Infof("Storage file %s has no damaged record slots", appConf.BinaryFilePath)
+	case "snapshot":
+		// snapshot is created in directory of snapshots when its path is not defined
+		snapshotPath := storage.SnapshotPath(appConf.SnapshotDir, time.Now())
+
+		if len(args) > 1 {
+			snapshotPath = args[1]
+		}
+
+		storageService, err := openStorage(appConf)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		snapshot, err := storageService.Snapshot(snapshotPath)
+		storageService.Close()
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := json.NewEncoder(os.Stdout).Encode(snapshot); err != nil {
+			log.Fatal(err)
+		}
+	case "restore":
+		if len(args) < 2 {
+			log.Fatal("Path of snapshot is missing")
+		}
+
+		if _, err := storage.RestoreSnapshot(args[1], appConf.BinaryFilePath); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("Unknown command %s", args[0])
+	}
 }
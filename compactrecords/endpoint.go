@@ -0,0 +1,30 @@
+package compactrecords
+
+import (
+	"encoding/json"
+	"interviewtest/tools"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MakePostCompactEndpoint function create POST endpoint for compaction of file storage
+func MakePostCompactEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		compactRes, err := service.Compact()
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(response).Encode(compactRes); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Compaction was successful, %d records moved", len(compactRes.IDMapping))
+	}
+}
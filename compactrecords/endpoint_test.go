@@ -0,0 +1,82 @@
+package compactrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tmpStorageFilePath = "/tmp/compact_records.bin"
+
+func TestCompactRecordsSuccessful(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 4; i++ {
+		rec := record.Record{
+			IntValue:  int64(i),
+			StrValue:  "foo",
+			BoolValue: false,
+			TimeValue: &testingTime,
+		}
+
+		if _, err := fileStorageService.CreateRecord(&rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.DeleteRecord(2); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("POST", "/admin/compact", nil)
+
+	rr := httptest.NewRecorder()
+
+	handler := MakePostCompactEndpoint(service)
+	handler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response struct {
+		IDMapping map[int64]int64 `json:"idMapping"`
+	}
+
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[int64]int64{3: 2, 4: 3}, response.IDMapping)
+
+	movedRecord, err := fileStorageService.GetRecord(3)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, movedRecord)
+	assert.Equal(t, int64(3), movedRecord.Id)
+	assert.Equal(t, int64(4), movedRecord.IntValue)
+
+	removedRecord, err := fileStorageService.GetRecord(4)
+
+	assert.NoError(t, err)
+	assert.Nil(t, removedRecord)
+}
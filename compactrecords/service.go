@@ -0,0 +1,38 @@
+package compactrecords
+
+import (
+	"interviewtest/record"
+
+	"github.com/pkg/errors"
+)
+
+// Service interface provides method for compaction of file storage
+type Service interface {
+	Compact() (*compactResponse, error)
+}
+
+type service struct {
+	record record.MaintenanceStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage maintenance
+func NewService(record record.MaintenanceStorage) Service {
+	return &service{record: record}
+}
+
+// Compact method removes deleted records from file storage
+// Method return response with mapping of old ids to new ids of moved records
+func (service *service) Compact() (*compactResponse, error) {
+	idMapping, err := service.record.Compact()
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &compactResponse{IDMapping: idMapping}, nil
+}
+
+type compactResponse struct {
+	IDMapping map[int64]int64 `json:"idMapping"`
+}
@@ -6,10 +6,12 @@ import (
 	"interviewtest/tools"
 	"net/http"
 
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 // MakePostCreateRecordEndpoint function create POST endpoint for create record
+// request with header Idempotency-Key creates record once, replayed request gets the original response with header Idempotent-Replayed
 func MakePostCreateRecordEndpoint(service Service) http.HandlerFunc {
 	return func(response http.ResponseWriter, request *http.Request) {
 		var rec record.Record
@@ -21,14 +23,30 @@ func MakePostCreateRecordEndpoint(service Service) http.HandlerFunc {
 			return
 		}
 
-		creatRes, err := service.Create(&rec)
+		creatRes, err := service.Create(&rec, request.Header.Get("Idempotency-Key"))
+
+		if err != nil && errors.Is(err, record.ErrIdempotencyKeyReused) {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		if err != nil && errors.Is(err, record.ErrIdempotencyDisabled) {
+			tools.SetErrResponseWithStatusCode(response, record.ErrIdempotencyDisabled, http.StatusNotImplemented)
+			return
+		}
 
 		if err != nil {
-			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			tools.SetErrResponse(response, err)
 			return
 		}
 
 		response.Header().Set("Content-Type", "application/json")
+		response.Header().Set("ETag", tools.ETag(rec.Version))
+
+		if creatRes.replayed {
+			response.Header().Set("Idempotent-Replayed", "true")
+		}
+
 		response.WriteHeader(http.StatusCreated)
 
 		if err = json.NewEncoder(response).Encode(creatRes); err != nil {
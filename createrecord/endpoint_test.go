@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"interviewtest/record"
 	"interviewtest/storage"
+	"interviewtest/tools/testtools"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
 	"time"
@@ -26,8 +26,8 @@ func TestCreationValidRecords(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove(tmpStorageFilePath)
 		fileStorageService.Close()
 	})
 
@@ -115,8 +115,8 @@ func TestCreateRecordInternalServerError(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove(tmpStorageFilePath)
 		fileStorageService.Close()
 	})
 
@@ -169,8 +169,8 @@ func TestCreationNonValidRecordBadRequest(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove(tmpStorageFilePath)
 		fileStorageService.Close()
 	})
 
@@ -216,3 +216,126 @@ func TestCreationNonValidRecordBadRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateRecordStrValueTooLong(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath, storage.WithMaxStrLength(10))
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	rec := `{
+		"IntValue": 42,
+		"StrValue": "StrValue longer than limit",
+		"BoolValue": false,
+		"TimeValue": "2023-10-14T12:00:00Z"
+	}`
+
+	req, err := http.NewRequest("POST", "/create", strings.NewReader(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler := MakePostCreateRecordEndpoint(service)
+	handler(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), record.ErrStrValueTooLong.Error())
+}
+
+func TestCreateRecordWithIdempotencyKey(t *testing.T) {
+	const storageFilePath = "/tmp/idempotent_create_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath, storage.WithIdempotencyTTL(time.Hour))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	rec := `{"IntValue": 42, "StrValue": "foo", "TimeValue": "2023-10-14T12:00:00Z"}`
+	otherRec := `{"IntValue": 43, "StrValue": "foo", "TimeValue": "2023-10-14T12:00:00Z"}`
+
+	tests := []struct {
+		name             string
+		idempotencyKey   string
+		inputData        string
+		expectedCode     int
+		expectedID       int64
+		expectedReplayed string
+	}{{
+		name:           "Create record with key - expected status code 201",
+		idempotencyKey: "a1",
+		inputData:      rec,
+		expectedCode:   http.StatusCreated,
+		expectedID:     1,
+	}, {
+		name:             "Replay request with key - expected original response",
+		idempotencyKey:   "a1",
+		inputData:        rec,
+		expectedCode:     http.StatusCreated,
+		expectedID:       1,
+		expectedReplayed: "true",
+	}, {
+		name:           "Reuse key for other record - expected status code 422",
+		idempotencyKey: "a1",
+		inputData:      otherRec,
+		expectedCode:   http.StatusUnprocessableEntity,
+	}, {
+		name:           "Too long key - expected status code 400",
+		idempotencyKey: strings.Repeat("k", 256),
+		inputData:      rec,
+		expectedCode:   http.StatusBadRequest,
+	}, {
+		name:         "Create record without key - expected new record",
+		inputData:    rec,
+		expectedCode: http.StatusCreated,
+		expectedID:   2,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/records", strings.NewReader(tt.inputData))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := MakePostCreateRecordEndpoint(service)
+			handler(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.expectedReplayed, rr.Header().Get("Idempotent-Replayed"))
+
+			if tt.expectedCode != http.StatusCreated {
+				return
+			}
+
+			assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+
+			var response map[string]int64
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+			assert.Equal(t, tt.expectedID, response["ID"])
+		})
+	}
+}
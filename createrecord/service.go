@@ -1,7 +1,9 @@
 package createrecord
 
 import (
+	"fmt"
 	"interviewtest/record"
+	"interviewtest/tools"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
@@ -9,7 +11,7 @@ import (
 
 // Service interface provides method for creating record in file storage
 type Service interface {
-	Create(rec *record.Record) (*createResponse, error)
+	Create(rec *record.Record, idempotencyKey string) (*createResponse, error)
 }
 
 type service struct {
@@ -24,11 +26,14 @@ func NewService(record record.Storage) Service {
 
 // Create method creating validating and creating record
 // Method return response with id of new record
-func (service *service) Create(rec *record.Record) (*createResponse, error) {
-	validate := validator.New()
+// record with non-empty idempotency key is created only once, response of the first request is returned for the same key
+func (service *service) Create(rec *record.Record, idempotencyKey string) (*createResponse, error) {
+	if err := Validate(rec); err != nil {
+		return nil, err
+	}
 
-	if err := validate.Struct(rec); err != nil {
-		return nil, err.(validator.ValidationErrors)
+	if idempotencyKey != "" {
+		return service.createIdempotent(rec, idempotencyKey)
 	}
 
 	id, err := service.record.CreateRecord(rec)
@@ -40,6 +45,37 @@ func (service *service) Create(rec *record.Record) (*createResponse, error) {
 	return &createResponse{RecordID: id}, nil
 }
 
+// Validate function validates record by rules of created records
+// validator.ValidationErrors with all invalid fields is returned for invalid record
+func Validate(rec *record.Record) error {
+	if err := validator.New().Struct(rec); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+
+	return nil
+}
+
+// createIdempotent method creates record once for idempotency key
+func (service *service) createIdempotent(rec *record.Record, idempotencyKey string) (*createResponse, error) {
+	if len(idempotencyKey) > record.MaxIdempotencyKeyLength {
+		return nil, tools.FieldErrors{{
+			Field:   "Idempotency-Key",
+			Message: fmt.Sprintf("key is longer than %d bytes", record.MaxIdempotencyKeyLength),
+		}}
+	}
+
+	created, err := service.record.CreateRecordIdempotent(idempotencyKey, rec)
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &createResponse{RecordID: rec.Id, replayed: !created}, nil
+}
+
+// createResponse structure of response with id of created record
+// replayed is set when record was created by previous request with the same idempotency key
 type createResponse struct {
 	RecordID int64 `json:"ID"`
+	replayed bool
 }
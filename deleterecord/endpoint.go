@@ -10,6 +10,7 @@ import (
 )
 
 // MakeDeleteRecordEndpoint function create DELETE endpoint for delete record
+// record is deleted only when If-Match header matches ETag of stored record, otherwise 412 is returned
 func MakeDeleteRecordEndpoint(service Service) http.HandlerFunc {
 	return func(response http.ResponseWriter, request *http.Request) {
 		recID := mux.Vars(request)["id"]
@@ -21,7 +22,7 @@ func MakeDeleteRecordEndpoint(service Service) http.HandlerFunc {
 			return
 		}
 
-		if err := service.Delete(id); err != nil {
+		if err := service.Delete(id, request.Header.Get("If-Match")); err != nil {
 			tools.SetErrResponse(response, err)
 			return
 		}
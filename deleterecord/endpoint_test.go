@@ -1,11 +1,11 @@
 package deleterecord
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"interviewtest/record"
 	"interviewtest/storage"
+	"interviewtest/tools/testtools"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -26,8 +26,8 @@ func TestDeleteRecordSuccessful(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove(tmpStorageFilePath)
 		fileStorageService.Close()
 	})
 
@@ -51,16 +51,10 @@ func TestDeleteRecordSuccessful(t *testing.T) {
 		TimeValue: &testingTime,
 	}
 
-	file, err := os.OpenFile(tmpStorageFilePath, os.O_RDWR|os.O_CREATE, os.ModePerm)
-
-	if err != nil {
-		t.Error(err)
+	if _, err := fileStorageService.CreateRecord(&record1); err != nil {
+		t.Fatal(err)
 	}
 
-	writerRecord(&record1, file)
-
-	file.Close()
-
 	type args struct {
 		service Service
 	}
@@ -79,7 +73,7 @@ func TestDeleteRecordSuccessful(t *testing.T) {
 		idURLParam:   int64(1),
 	}}
 
-	file, err = os.Open(tmpStorageFilePath)
+	file, err := os.Open(tmpStorageFilePath)
 
 	if err != nil {
 		t.Error(err)
@@ -119,8 +113,8 @@ func TestDeleteRecordNotFound(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove(tmpStorageFilePath)
 		fileStorageService.Close()
 	})
 
@@ -136,16 +130,10 @@ func TestDeleteRecordNotFound(t *testing.T) {
 		TimeValue: &testingTime,
 	}
 
-	file, err := os.OpenFile(tmpStorageFilePath, os.O_RDWR|os.O_CREATE, os.ModePerm)
-
-	if err != nil {
-		t.Error(err)
+	if _, err := fileStorageService.CreateRecord(&record1); err != nil {
+		t.Fatal(err)
 	}
 
-	writerRecord(&record1, file)
-
-	file.Close()
-
 	type args struct {
 		service Service
 	}
@@ -160,12 +148,6 @@ func TestDeleteRecordNotFound(t *testing.T) {
 		idURLParam: int64(99),
 	}}
 
-	file, err = os.Open(tmpStorageFilePath)
-
-	if err != nil {
-		t.Error(err)
-	}
-
 	for _, tst := range tests {
 		tt := tst
 		t.Run(tt.name, func(t *testing.T) {
@@ -185,37 +167,65 @@ func TestDeleteRecordNotFound(t *testing.T) {
 	}
 }
 
-// writeRecord function for test
-func writerRecord(rec *record.Record, file *os.File) {
-	binary.Write(file, binary.LittleEndian, rec.Id)
-	binary.Write(file, binary.LittleEndian, rec.IntValue)
+func TestDeleteRecordWithIfMatch(t *testing.T) {
+	const storageFilePath = "/tmp/delete_if_match_records.bin"
 
-	strBytes := []byte(rec.StrValue)
-	strBytes = append(strBytes, make([]byte, 64-len(strBytes))...)
-	file.Write(strBytes)
+	fileStorageService, err := storage.NewService(storageFilePath)
 
-	boolByte := byte(0)
-	if rec.BoolValue {
-		boolByte = byte(1)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	file.Write([]byte{boolByte})
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
 
-	timeBytes, _ := rec.TimeValue.MarshalBinary()
-	timeLen := len(timeBytes)
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
 
-	if timeLen < 16 {
-		padding := make([]byte, 16-timeLen)
-		timeBytes = append(timeBytes, padding...)
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
 	}
 
-	file.Write(timeBytes)
-	file.Write([]byte{'\n'})
+	tests := []struct {
+		name         string
+		ifMatch      string
+		expectedCode int
+	}{{
+		name:         "Delete with old ETag - expected status code 412",
+		ifMatch:      `"0"`,
+		expectedCode: http.StatusPreconditionFailed,
+	}, {
+		name:         "Delete with current ETag - expected status code 204",
+		ifMatch:      `"2", "1"`,
+		expectedCode: http.StatusNoContent,
+	}, {
+		name:         "Delete of deleted record - expected status code 404",
+		ifMatch:      `"1"`,
+		expectedCode: http.StatusNotFound,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}", MakeDeleteRecordEndpoint(service)).Methods(http.MethodDelete)
+
+			req, _ := http.NewRequest("DELETE", "/records/1", nil)
+			req.Header.Set("If-Match", tt.ifMatch)
 
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
 }
 
 func readFile(targetID int64, file *os.File) *record.Record {
-	_, _ = file.Seek(0, io.SeekStart)
+	// skip 64 bytes header of file
+	_, _ = file.Seek(64, io.SeekStart)
 
 	for {
 		var rec record.Record
@@ -224,29 +234,38 @@ func readFile(targetID int64, file *os.File) *record.Record {
 			return nil
 		}
 
+		// skip time of delete
+		file.Seek(8, io.SeekCurrent)
+
+		binary.Read(file, binary.LittleEndian, &rec.Version)
 		binary.Read(file, binary.LittleEndian, &rec.IntValue)
 
+		var strLength uint32
+		binary.Read(file, binary.LittleEndian, &strLength)
+
+		// test records have only short StrValue stored in record
 		strBytes := make([]byte, 64)
 		file.Read(strBytes)
 
-		rec.StrValue = string(bytes.TrimRight(strBytes, string(rune(0))))
+		rec.StrValue = string(strBytes[:strLength])
 
 		boolByte := make([]byte, 1)
 		file.Read(boolByte)
 
 		rec.BoolValue = boolByte[0] != 0
 
-		timeBytes := make([]byte, 16)
-		file.Read(timeBytes)
-		timeBytes = bytes.Trim(timeBytes, "\x00")
+		var seconds int64
+		binary.Read(file, binary.LittleEndian, &seconds)
 
-		var t time.Time
+		var nanoseconds uint32
+		binary.Read(file, binary.LittleEndian, &nanoseconds)
 
-		t.UnmarshalBinary(timeBytes)
+		// test records are in local time zone, skip zone offset, checksum and new line
+		file.Seek(9, io.SeekCurrent)
 
-		rec.TimeValue = &t
+		t := time.Unix(seconds, int64(nanoseconds)).In(time.Local)
 
-		file.Seek(1, io.SeekCurrent)
+		rec.TimeValue = &t
 
 		if rec.Id == targetID {
 			return &rec
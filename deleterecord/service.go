@@ -9,7 +9,7 @@ import (
 
 // Service interface provides method for delete record in file storage
 type Service interface {
-	Delete(id int64) error
+	Delete(id int64, ifMatch string) error
 }
 
 type service struct {
@@ -23,8 +23,11 @@ func NewService(record record.Storage) Service {
 }
 
 // Delete method for delete record by id
-func (service *service) Delete(id int64) error {
-	successfulDeleteRecord, err := service.record.DeleteRecord(id)
+// record is deleted only when ifMatch is empty or matches version of record
+func (service *service) Delete(id int64, ifMatch string) error {
+	successfulDeleteRecord, err := service.record.DeleteRecordIf(id, func(version int64) error {
+		return tools.CheckIfMatch(ifMatch, version)
+	})
 	if err != nil {
 		return errors.WithStack(err)
 	}
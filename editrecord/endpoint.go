@@ -11,7 +11,17 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// ifNoneMatchHeader header of request which creates record, value '*' creates record only when it does not exist
+	ifNoneMatchHeader = "If-None-Match"
+	// ifMatchHeader header of request which edits record only when it matches ETag of stored record
+	ifMatchHeader = "If-Match"
+)
+
 // MakePutRecordEndpoint function create PUT endpoint for record modification
+// record with id from path is created instead when request has header If-None-Match: *
+// record is edited only when If-Match header matches ETag of stored record, otherwise 412 is returned
+// ETag of written record is set into response
 func MakePutRecordEndpoint(service Service) http.HandlerFunc {
 	return func(response http.ResponseWriter, request *http.Request) {
 		var rec record.Record
@@ -32,11 +42,26 @@ func MakePutRecordEndpoint(service Service) http.HandlerFunc {
 			return
 		}
 
-		if err := service.Edit(id, &rec); err != nil {
+		if request.Header.Get(ifNoneMatchHeader) == "*" {
+			if err := service.Create(id, &rec); err != nil {
+				tools.SetErrResponse(response, err)
+				return
+			}
+
+			response.Header().Set("ETag", tools.ETag(rec.Version))
+			response.WriteHeader(http.StatusCreated)
+
+			log.Debugf("Create record %s was successful", recID)
+			return
+		}
+
+		if err := service.Edit(id, &rec, request.Header.Get(ifMatchHeader)); err != nil {
 			tools.SetErrResponse(response, err)
 			return
 		}
 
+		response.Header().Set("ETag", tools.ETag(rec.Version))
+
 		log.Debugf("Edit record %s was successful", recID)
 	}
 }
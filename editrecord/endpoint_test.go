@@ -6,9 +6,9 @@ import (
 	"fmt"
 	"interviewtest/record"
 	"interviewtest/storage"
+	"interviewtest/tools/testtools"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
 	"time"
@@ -28,7 +28,7 @@ func TestEditRecordSuccessful(t *testing.T) {
 		t.Error(err)
 	}
 
-	defer os.Remove(tmpStorageFilePath)
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer fileStorageService.Close()
 
 	service := NewService(fileStorageService)
@@ -130,7 +130,8 @@ func TestEditRecordSuccessful(t *testing.T) {
 		assert.Equal(t, tt.expectedData.IntValue, persistedData.IntValue)
 		assert.Equal(t, tt.expectedData.StrValue, persistedData.StrValue)
 		assert.Equal(t, tt.expectedData.BoolValue, persistedData.BoolValue)
-		assert.Equal(t, tt.expectedData.TimeValue, persistedData.TimeValue)
+		// time zone of local time is not transferred in JSON, only its offset
+		assert.Equal(t, tt.expectedData.TimeValue.Format(time.RFC3339Nano), persistedData.TimeValue.Format(time.RFC3339Nano))
 	}
 }
 
@@ -141,8 +142,8 @@ func TestEditRecordInternalServerError(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove("/tmp/tmp_records.bin")
 		fileStorageService.Close()
 	})
 
@@ -196,3 +197,287 @@ func TestEditRecordInternalServerError(t *testing.T) {
 		})
 	}
 }
+
+func TestEditRecordNotFound(t *testing.T) {
+	const storageFilePath = "/tmp/edit_not_found_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.DeleteRecord(2); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := `{
+		"IntValue": 142,
+		"StrValue": "foo+1",
+		"BoolValue": true,
+		"TimeValue": "2023-12-31T12:42:59.987654321Z"
+	}`
+
+	tests := []struct {
+		name       string
+		idURLParam int64
+	}{{
+		name:       "Edit deleted record - expected status code 404",
+		idURLParam: int64(2),
+	}, {
+		name:       "Edit record after end of file - expected status code 404",
+		idURLParam: int64(3),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}", MakePutRecordEndpoint(service)).Methods(http.MethodPut)
+
+			req, _ := http.NewRequest("PUT", fmt.Sprintf("/records/%d", tt.idURLParam), strings.NewReader(rec))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			persistedData, err := fileStorageService.GetRecord(tt.idURLParam)
+			assert.NoError(t, err)
+			assert.Nil(t, persistedData)
+		})
+	}
+}
+
+func TestEditRecordPreservesPathID(t *testing.T) {
+	const storageFilePath = "/tmp/edit_path_id_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.Handle("/records/{id:[0-9]+}", MakePutRecordEndpoint(service)).Methods(http.MethodPut)
+
+	// body without id does not delete record
+	req, _ := http.NewRequest("PUT", "/records/1", strings.NewReader(`{
+		"IntValue": 142,
+		"StrValue": "foo+1",
+		"BoolValue": true,
+		"TimeValue": "2023-12-31T12:42:59.987654321Z"
+	}`))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	persistedData, err := fileStorageService.GetRecord(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, persistedData)
+	assert.Equal(t, int64(1), persistedData.Id)
+	assert.Equal(t, int64(142), persistedData.IntValue)
+}
+
+func TestCreateRecordWithIfNoneMatch(t *testing.T) {
+	const storageFilePath = "/tmp/edit_create_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.DeleteRecord(2); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := `{
+		"IntValue": 142,
+		"StrValue": "foo+1",
+		"BoolValue": true,
+		"TimeValue": "2023-12-31T12:42:59.987654321Z"
+	}`
+
+	tests := []struct {
+		name         string
+		idURLParam   int64
+		expectedCode int
+	}{{
+		name:         "Create record in deleted slot - expected status code 201",
+		idURLParam:   int64(2),
+		expectedCode: http.StatusCreated,
+	}, {
+		name:         "Create record after end of file - expected status code 201",
+		idURLParam:   int64(5),
+		expectedCode: http.StatusCreated,
+	}, {
+		name:         "Create existing record - expected status code 412",
+		idURLParam:   int64(1),
+		expectedCode: http.StatusPreconditionFailed,
+	}, {
+		name:         "Create record far after end of file - expected status code 400",
+		idURLParam:   int64(1000000),
+		expectedCode: http.StatusBadRequest,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}", MakePutRecordEndpoint(service)).Methods(http.MethodPut)
+
+			req, _ := http.NewRequest("PUT", fmt.Sprintf("/records/%d", tt.idURLParam), strings.NewReader(rec))
+			req.Header.Set("If-None-Match", "*")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusCreated {
+				return
+			}
+
+			persistedData, err := fileStorageService.GetRecord(tt.idURLParam)
+			assert.NoError(t, err)
+			assert.NotNil(t, persistedData)
+			assert.Equal(t, tt.idURLParam, persistedData.Id)
+			assert.Equal(t, int64(142), persistedData.IntValue)
+		})
+	}
+
+	// slots skipped by record created after end of file are empty
+	skippedRecord, err := fileStorageService.GetRecord(3)
+	assert.NoError(t, err)
+	assert.Nil(t, skippedRecord)
+}
+
+func TestEditRecordWithIfMatch(t *testing.T) {
+	const storageFilePath = "/tmp/edit_if_match_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := `{
+		"IntValue": 142,
+		"StrValue": "foo+1",
+		"BoolValue": true,
+		"TimeValue": "2023-12-31T12:42:59.987654321Z"
+	}`
+
+	tests := []struct {
+		name         string
+		ifMatch      string
+		idURLParam   int64
+		expectedCode int
+		expectedETag string
+	}{{
+		name:         "Edit with old ETag - expected status code 412",
+		ifMatch:      `"2"`,
+		idURLParam:   int64(1),
+		expectedCode: http.StatusPreconditionFailed,
+	}, {
+		name:         "Edit with weak ETag - expected status code 412",
+		ifMatch:      `W/"1"`,
+		idURLParam:   int64(1),
+		expectedCode: http.StatusPreconditionFailed,
+	}, {
+		name:         "Edit with current ETag - expected status code 200",
+		ifMatch:      `"1"`,
+		idURLParam:   int64(1),
+		expectedCode: http.StatusOK,
+		expectedETag: `"2"`,
+	}, {
+		name:         "Edit with star - expected status code 200",
+		ifMatch:      "*",
+		idURLParam:   int64(1),
+		expectedCode: http.StatusOK,
+		expectedETag: `"3"`,
+	}, {
+		name:         "Edit of not existing record - expected status code 404",
+		ifMatch:      "*",
+		idURLParam:   int64(99),
+		expectedCode: http.StatusNotFound,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}", MakePutRecordEndpoint(service)).Methods(http.MethodPut)
+
+			req, _ := http.NewRequest("PUT", fmt.Sprintf("/records/%d", tt.idURLParam), strings.NewReader(rec))
+			req.Header.Set("If-Match", tt.ifMatch)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.expectedETag, rr.Header().Get("ETag"))
+		})
+	}
+
+	persistedData, err := fileStorageService.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), persistedData.Version)
+	assert.Equal(t, int64(142), persistedData.IntValue)
+}
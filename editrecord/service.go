@@ -10,7 +10,8 @@ import (
 
 // Service interface provides method for edit record in file storage
 type Service interface {
-	Edit(id int64, rec *record.Record) error
+	Edit(id int64, rec *record.Record, ifMatch string) error
+	Create(id int64, rec *record.Record) error
 }
 
 type service struct {
@@ -24,13 +25,18 @@ func NewService(record record.Storage) Service {
 }
 
 // Edit method for validating and editing record
-func (service *service) Edit(id int64, rec *record.Record) error {
+// record is replaced only when ifMatch is empty or matches version of stored record, version of written record is set into rec
+func (service *service) Edit(id int64, rec *record.Record, ifMatch string) error {
 	validate := validator.New()
 
 	if err := validate.Struct(rec); err != nil {
 		return err.(validator.ValidationErrors)
 	}
 
+	if ifMatch != "" {
+		return service.editIfMatch(id, rec, ifMatch)
+	}
+
 	updateID, err := service.record.EditRecord(id, rec)
 
 	if err != nil {
@@ -43,3 +49,40 @@ func (service *service) Edit(id int64, rec *record.Record) error {
 
 	return nil
 }
+
+// editIfMatch method replaces record under storage lock after version of stored record is checked
+func (service *service) editIfMatch(id int64, rec *record.Record, ifMatch string) error {
+	updated, err := service.record.UpdateRecord(id, func(stored *record.Record) error {
+		if err := tools.CheckIfMatch(ifMatch, stored.Version); err != nil {
+			return err
+		}
+
+		*stored = *rec
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if updated == nil {
+		return tools.RecordNotFound
+	}
+
+	rec.Id = updated.Id
+	rec.Version = updated.Version
+
+	return nil
+}
+
+// Create method for validating and creating record with id given by request
+// record.ErrRecordExists is returned when record with the id exists
+func (service *service) Create(id int64, rec *record.Record) error {
+	validate := validator.New()
+
+	if err := validate.Struct(rec); err != nil {
+		return err.(validator.ValidationErrors)
+	}
+
+	return errors.WithStack(service.record.CreateRecordAt(id, rec))
+}
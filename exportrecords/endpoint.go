@@ -0,0 +1,218 @@
+package exportrecords
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"interviewtest/query"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	formatParam  = "format"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// content types of export formats
+var contentTypes = map[string]string{
+	formatNDJSON: "application/x-ndjson",
+	formatCSV:    "text/csv",
+}
+
+// csvHeader names of columns of CSV export
+var csvHeader = []string{query.FieldID, query.FieldIntValue, query.FieldStrValue, query.FieldBoolValue, query.FieldTimeValue}
+
+// MakeGetExportRecordsEndpoint function create GET endpoint for export of all records as NDJSON or CSV
+// format is chosen by query parameter format or by Accept header, NDJSON is default
+// other query parameters are filters of records as in list of records, records are exported in order of id
+func MakeGetExportRecordsEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		params := request.URL.Query()
+
+		format, err := exportFormat(params.Get(formatParam), request.Header.Get("Accept"))
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		if format == "" {
+			tools.SetErrResponseWithStatusCode(response, errors.New("export is available as application/x-ndjson or text/csv"), http.StatusNotAcceptable)
+			return
+		}
+
+		params.Del(formatParam)
+
+		q, err := query.Parse(params)
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		if q.Sorted() {
+			tools.SetErrResponse(response, tools.FieldErrors{{Field: query.SortParam, Message: "records are exported in order of id"}})
+			return
+		}
+
+		response.Header().Set("Content-Type", contentTypes[format])
+		response.Header().Set("Content-Disposition", `attachment; filename="records.`+format+`"`)
+
+		writer := newRecordWriter(format, response)
+		exported := 0
+
+		err = service.Export(q, func(rec *record.Record) error {
+			exported++
+			return writer.write(rec)
+		})
+
+		if err == nil {
+			err = writer.flush()
+		}
+
+		// status is sent with the first records, failed export is recognized by incomplete output
+		if err != nil {
+			log.Errorf("Export of records failed after %d records: %s", exported, err)
+			return
+		}
+
+		log.Debugf("Export of %d records was successful", exported)
+	}
+}
+
+// exportFormat function returns format given by parameter or the first format accepted by client
+// empty format is returned when client does not accept any format
+func exportFormat(param, accept string) (string, error) {
+	if param != "" {
+		if _, ok := contentTypes[param]; !ok {
+			return "", tools.FieldErrors{{Field: formatParam, Message: "format must be ndjson or csv"}}
+		}
+
+		return param, nil
+	}
+
+	if accept == "" {
+		return formatNDJSON, nil
+	}
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "application/x-ndjson", "application/ndjson", "application/*", "*/*":
+			return formatNDJSON, nil
+		case "text/csv", "text/*":
+			return formatCSV, nil
+		}
+	}
+
+	return "", nil
+}
+
+// recordWriter writes exported records in chosen format, output is flushed to client after every chunk of records
+type recordWriter struct {
+	response http.ResponseWriter
+	buffer   *bufio.Writer
+	csv      *csv.Writer
+	encoder  *json.Encoder
+	records  int
+}
+
+// newRecordWriter function creates writer of records in given format
+func newRecordWriter(format string, response http.ResponseWriter) *recordWriter {
+	writer := &recordWriter{response: response, buffer: bufio.NewWriter(response)}
+
+	if format == formatCSV {
+		writer.csv = csv.NewWriter(writer.buffer)
+	} else {
+		writer.encoder = json.NewEncoder(writer.buffer)
+	}
+
+	return writer
+}
+
+// write method writes one record, header of CSV is written before the first record
+func (writer *recordWriter) write(rec *record.Record) error {
+	var err error
+
+	if writer.csv == nil {
+		err = writer.encoder.Encode(rec)
+	} else {
+		if writer.records == 0 {
+			err = writer.csv.Write(csvHeader)
+		}
+
+		if err == nil {
+			err = writer.csv.Write(csvRecord(rec))
+		}
+	}
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	writer.records++
+
+	if writer.records%chunkSize == 0 {
+		return writer.flush()
+	}
+
+	return nil
+}
+
+// flush method sends written records to client, CSV header is written when no record was exported
+func (writer *recordWriter) flush() error {
+	if writer.csv != nil {
+		if writer.records == 0 {
+			if err := writer.csv.Write(csvHeader); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		writer.csv.Flush()
+
+		if err := writer.csv.Error(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := writer.buffer.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if flusher, ok := writer.response.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// csvRecord function returns fields of record as CSV columns
+func csvRecord(rec *record.Record) []string {
+	timeValue := ""
+
+	if rec.TimeValue != nil {
+		timeValue = rec.TimeValue.Format(time.RFC3339Nano)
+	}
+
+	return []string{
+		strconv.FormatInt(rec.Id, 10),
+		strconv.FormatInt(rec.IntValue, 10),
+		rec.StrValue,
+		strconv.FormatBool(rec.BoolValue),
+		timeValue,
+	}
+}
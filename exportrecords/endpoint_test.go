@@ -0,0 +1,127 @@
+package exportrecords
+
+import (
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportRecords(t *testing.T) {
+	const storageFilePath = "/tmp/export_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: int64(i * 10), StrValue: "foo, bar", BoolValue: i%2 == 0, TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.DeleteRecord(2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                string
+		url                 string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{{
+		name:                "Export without format - expected NDJSON",
+		url:                 "/records/export",
+		expectedCode:        http.StatusOK,
+		expectedContentType: "application/x-ndjson",
+		expectedBody: `{"id":1,"IntValue":10,"StrValue":"foo, bar","BoolValue":false,"TimeValue":"2023-12-31T12:42:59.987654321Z"}
+{"id":3,"IntValue":30,"StrValue":"foo, bar","BoolValue":false,"TimeValue":"2023-12-31T12:42:59.987654321Z"}
+`,
+	}, {
+		name:                "Export with Accept header - expected CSV",
+		url:                 "/records/export",
+		accept:              "application/json;q=0.9, text/csv",
+		expectedCode:        http.StatusOK,
+		expectedContentType: "text/csv",
+		expectedBody: `id,IntValue,StrValue,BoolValue,TimeValue
+1,10,"foo, bar",false,2023-12-31T12:42:59.987654321Z
+3,30,"foo, bar",false,2023-12-31T12:42:59.987654321Z
+`,
+	}, {
+		name:                "Export with format parameter and filter - expected CSV",
+		url:                 "/records/export?format=csv&IntValue.gte=20",
+		accept:              "application/x-ndjson",
+		expectedCode:        http.StatusOK,
+		expectedContentType: "text/csv",
+		expectedBody: `id,IntValue,StrValue,BoolValue,TimeValue
+3,30,"foo, bar",false,2023-12-31T12:42:59.987654321Z
+`,
+	}, {
+		name:                "Export without matching records - expected CSV header",
+		url:                 "/records/export?format=csv&IntValue=99",
+		expectedCode:        http.StatusOK,
+		expectedContentType: "text/csv",
+		expectedBody:        "id,IntValue,StrValue,BoolValue,TimeValue\n",
+	}, {
+		name:         "Export with not accepted format - expected status code 406",
+		url:          "/records/export",
+		accept:       "application/xml",
+		expectedCode: http.StatusNotAcceptable,
+	}, {
+		name:         "Export with invalid format - expected status code 400",
+		url:          "/records/export?format=xml",
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Export with invalid filter - expected status code 400",
+		url:          "/records/export?IntValue.gte=foo",
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Export sorted by other field - expected status code 400",
+		url:          "/records/export?sort=-IntValue",
+		expectedCode: http.StatusBadRequest,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/export", MakeGetExportRecordsEndpoint(service)).Methods(http.MethodGet)
+
+			req, _ := http.NewRequest("GET", tt.url, nil)
+
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tt.expectedContentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package exportrecords
+
+import (
+	"interviewtest/query"
+	"interviewtest/record"
+
+	"github.com/pkg/errors"
+)
+
+// chunkSize number of records read under one lock of storage, records are written to client between chunks
+const chunkSize = 1000
+
+// Service interface provides method for export of all records matching filters
+type Service interface {
+	Export(q *query.Query, fn func(rec *record.Record) error) error
+}
+
+type service struct {
+	record record.ReadingStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage
+func NewService(record record.Storage) Service {
+	return &service{record: record}
+}
+
+// Export method calls function for every record matching the query in order of id
+// records are read in chunks, so storage is not locked while records are written and whole file is not loaded into memory
+// export stops at first error returned by function
+func (service *service) Export(q *query.Query, fn func(rec *record.Record) error) error {
+	for _, valueRange := range q.Ranges() {
+		ids, err := service.record.LookupRangeAfter(valueRange, 0, chunkSize)
+
+		if errors.Is(err, record.ErrNotIndexed) {
+			continue
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return service.exportIndexed(q, valueRange, ids, fn)
+	}
+
+	return service.exportScan(q, fn)
+}
+
+// exportIndexed method exports matching records found by secondary index, ids of range are looked up in chunks
+// records of chunk are filtered by all filters of query
+func (service *service) exportIndexed(q *query.Query, valueRange record.Range, ids []int64, fn func(rec *record.Record) error) error {
+	for {
+		for _, id := range ids {
+			rec, err := service.record.GetRecord(id)
+
+			// corrupted record is skipped as in scan, record could be deleted after lookup
+			if err != nil && !errors.Is(err, record.ErrCorruptRecord) {
+				return errors.WithStack(err)
+			} else if rec == nil || !q.Match(rec) {
+				continue
+			}
+
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) < chunkSize {
+			return nil
+		}
+
+		var err error
+
+		if ids, err = service.record.LookupRangeAfter(valueRange, ids[len(ids)-1], chunkSize); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
+
+// exportScan method exports matching records read by scans of storage in chunks
+func (service *service) exportScan(q *query.Query, fn func(rec *record.Record) error) error {
+	afterID := int64(0)
+	chunk := make([]*record.Record, 0, chunkSize)
+
+	for {
+		chunk = chunk[:0]
+		scanned := 0
+
+		err := service.record.ScanRecords(afterID, func(rec *record.Record) bool {
+			afterID = rec.Id
+			scanned++
+
+			if q.Match(rec) {
+				chunk = append(chunk, rec)
+			}
+
+			return scanned < chunkSize
+		})
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, rec := range chunk {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+
+		if scanned < chunkSize {
+			return nil
+		}
+	}
+}
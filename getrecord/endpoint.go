@@ -2,15 +2,20 @@ package getrecord
 
 import (
 	"encoding/json"
+	"interviewtest/record"
 	"interviewtest/tools"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 // MakeGetRecordEndpoint function create GET endpoint for get record
+// response has ETag header with version of record, 304 without body is returned when If-None-Match matches the version
+// record as it was at time given by query parameter asOf (RFC 3339) is read from history of record
 func MakeGetRecordEndpoint(service Service) http.HandlerFunc {
 	return func(response http.ResponseWriter, request *http.Request) {
 		recID := mux.Vars(request)["id"]
@@ -22,13 +27,38 @@ func MakeGetRecordEndpoint(service Service) http.HandlerFunc {
 			return
 		}
 
-		rec, err := service.GetRecord(id)
+		var rec *record.Record
+
+		if asOfValue := request.URL.Query().Get("asOf"); asOfValue != "" {
+			asOf, parseErr := time.Parse(time.RFC3339Nano, asOfValue)
+
+			if parseErr != nil {
+				tools.SetErrResponseWithStatusCode(response, tools.FieldErrors{{Field: "asOf", Message: "asOf is not RFC 3339 time"}}, http.StatusBadRequest)
+				return
+			}
+
+			rec, err = service.GetRecordAsOf(id, asOf)
+
+			if err != nil && errors.Is(err, record.ErrHistoryDisabled) {
+				tools.SetErrResponseWithStatusCode(response, record.ErrHistoryDisabled, http.StatusNotImplemented)
+				return
+			}
+		} else {
+			rec, err = service.GetRecord(id)
+		}
 
 		if err != nil {
 			tools.SetErrResponse(response, err)
 			return
 		}
 
+		response.Header().Set("ETag", tools.ETag(rec.Version))
+
+		if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" && tools.MatchETag(ifNoneMatch, rec.Version, true) {
+			response.WriteHeader(http.StatusNotModified)
+			return
+		}
+
 		response.Header().Set("Content-Type", "application/json")
 
 		if err = json.NewEncoder(response).Encode(rec); err != nil {
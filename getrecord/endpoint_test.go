@@ -1,13 +1,14 @@
 package getrecord
 
 import (
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"interviewtest/record"
 	"interviewtest/storage"
+	"interviewtest/tools/testtools"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
 	"time"
@@ -25,8 +26,8 @@ func TestSuccessfulGetRecords(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove(tmpStorageFilePath)
 		fileStorageService.Close()
 	})
 
@@ -50,14 +51,13 @@ func TestSuccessfulGetRecords(t *testing.T) {
 		TimeValue: &testingTime,
 	}
 
-	file, err := os.OpenFile(tmpStorageFilePath, os.O_RDWR|os.O_CREATE, os.ModePerm)
-
-	if err != nil {
-		t.Error(err)
+	if _, err := fileStorageService.CreateRecord(&record1); err != nil {
+		t.Fatal(err)
 	}
 
-	writerRecord(&record1, file)
-	writerRecord(&record2, file)
+	if _, err := fileStorageService.CreateRecord(&record2); err != nil {
+		t.Fatal(err)
+	}
 
 	type args struct {
 		service Service
@@ -107,7 +107,8 @@ func TestSuccessfulGetRecords(t *testing.T) {
 			assert.Equal(t, tt.expected.IntValue, responseRecord.IntValue)
 			assert.Equal(t, tt.expected.StrValue, responseRecord.StrValue)
 			assert.Equal(t, tt.expected.BoolValue, responseRecord.BoolValue)
-			assert.Equal(t, tt.expected.TimeValue, responseRecord.TimeValue)
+			// time zone of local time is not transferred in JSON, only its offset
+			assert.Equal(t, tt.expected.TimeValue.Format(time.RFC3339Nano), responseRecord.TimeValue.Format(time.RFC3339Nano))
 		})
 	}
 }
@@ -121,8 +122,8 @@ func TestGetRecordNotFound(t *testing.T) {
 		t.Error(err)
 	}
 
+	testtools.CleanupFiles(t, tmpStorageFilePath)
 	defer t.Cleanup(func() {
-		os.Remove(tmpStorageFilePath)
 		fileStorageService.Close()
 	})
 
@@ -162,32 +163,219 @@ func TestGetRecordNotFound(t *testing.T) {
 	}
 }
 
-// writeRecord function for test
-func writerRecord(rec *record.Record, file *os.File) error {
-	binary.Write(file, binary.LittleEndian, rec.Id)
-	binary.Write(file, binary.LittleEndian, rec.IntValue)
+func TestGetCorruptRecord(t *testing.T) {
+	tmpStorageFilePath := "/tmp/tmp_get_corrupt_records.bin"
+
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
 
-	strBytes := []byte(rec.StrValue)
-	strBytes = append(strBytes, make([]byte, 64-len(strBytes))...)
-	file.Write(strBytes)
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
 
-	boolByte := byte(0)
-	if rec.BoolValue {
-		boolByte = byte(1)
+	record1 := record.Record{
+		IntValue:  42,
+		StrValue:  "foo",
+		BoolValue: false,
+		TimeValue: &testingTime,
 	}
 
-	file.Write([]byte{boolByte})
+	if _, err := fileStorageService.CreateRecord(&record1); err != nil {
+		t.Fatal(err)
+	}
 
-	timeBytes, _ := rec.TimeValue.MarshalBinary()
-	timeLen := len(timeBytes)
+	file, err := os.OpenFile(tmpStorageFilePath, os.O_RDWR, os.ModePerm)
 
-	if timeLen < 16 {
-		padding := make([]byte, 16-timeLen)
-		timeBytes = append(timeBytes, padding...)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	file.Write(timeBytes)
-	file.Write([]byte{'\n'})
+	// damage IntValue of record, record follows 64 bytes header, 8 bytes id, 8 bytes time of delete and 8 bytes version
+	file.WriteAt([]byte{43}, 64+24)
+	file.Close()
+
+	router := mux.NewRouter()
+	router.Handle("/records/{id:[0-9]+}", MakeGetRecordEndpoint(service)).Methods(http.MethodGet)
+
+	req, _ := http.NewRequest("GET", "/records/1", nil)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), record.ErrCorruptRecord.Error())
+}
+
+func TestGetRecordETag(t *testing.T) {
+	tmpStorageFilePath := "/tmp/tmp_get_etag_records.bin"
 
-	return nil
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	rec := record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}
+
+	if _, err := fileStorageService.CreateRecord(&rec); err != nil {
+		t.Fatal(err)
+	}
+
+	// edited record has second version
+	if _, err := fileStorageService.EditRecord(1, &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		ifNoneMatch  string
+		expectedCode int
+	}{{
+		name:         "Without If-None-Match - expected status code 200",
+		expectedCode: http.StatusOK,
+	}, {
+		name:         "If-None-Match with current ETag - expected status code 304",
+		ifNoneMatch:  `"2"`,
+		expectedCode: http.StatusNotModified,
+	}, {
+		name:         "If-None-Match with weak current ETag in list - expected status code 304",
+		ifNoneMatch:  `"1", W/"2"`,
+		expectedCode: http.StatusNotModified,
+	}, {
+		name:         "If-None-Match with star - expected status code 304",
+		ifNoneMatch:  "*",
+		expectedCode: http.StatusNotModified,
+	}, {
+		name:         "If-None-Match with old ETag - expected status code 200",
+		ifNoneMatch:  `"1"`,
+		expectedCode: http.StatusOK,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}", MakeGetRecordEndpoint(service)).Methods(http.MethodGet)
+
+			req, _ := http.NewRequest("GET", "/records/1", nil)
+
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+			if tt.expectedCode == http.StatusNotModified {
+				assert.Empty(t, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetRecordAsOf(t *testing.T) {
+	tmpStorageFilePath := "/tmp/tmp_get_as_of_records.bin"
+
+	fileStorageService, err := storage.NewService(tmpStorageFilePath, storage.WithHistory(true))
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fileStorageService.EditRecord(1, &record.Record{IntValue: 43, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := fileStorageService.RecordHistory(1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createdAt := history[0].ChangedAt
+	editedAt := history[1].ChangedAt
+
+	tests := []struct {
+		name             string
+		asOf             string
+		expectedCode     int
+		expectedIntValue int64
+		expectedETag     string
+	}{{
+		name:             "Read record before edit - expected status code 200",
+		asOf:             editedAt.Add(-time.Nanosecond).Format(time.RFC3339Nano),
+		expectedCode:     http.StatusOK,
+		expectedIntValue: 42,
+		expectedETag:     `"1"`,
+	}, {
+		name:             "Read record after edit - expected status code 200",
+		asOf:             editedAt.Format(time.RFC3339Nano),
+		expectedCode:     http.StatusOK,
+		expectedIntValue: 43,
+		expectedETag:     `"2"`,
+	}, {
+		name:         "Read record before create - expected status code 404",
+		asOf:         createdAt.Add(-time.Second).Format(time.RFC3339),
+		expectedCode: http.StatusNotFound,
+	}, {
+		name:         "Read record at invalid time - expected status code 400",
+		asOf:         "yesterday",
+		expectedCode: http.StatusBadRequest,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}", MakeGetRecordEndpoint(service)).Methods(http.MethodGet)
+
+			req, _ := http.NewRequest("GET", "/records/1?asOf="+url.QueryEscape(tt.asOf), nil)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var responseRecord record.Record
+
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&responseRecord))
+			assert.Equal(t, tt.expectedIntValue, responseRecord.IntValue)
+			assert.Equal(t, tt.expectedETag, rr.Header().Get("ETag"))
+		})
+	}
 }
@@ -3,6 +3,7 @@ package getrecord
 import (
 	"interviewtest/record"
 	"interviewtest/tools"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -10,6 +11,7 @@ import (
 // Service interface provides method for reading record from file storage
 type Service interface {
 	GetRecord(id int64) (*record.Record, error)
+	GetRecordAsOf(id int64, asOf time.Time) (*record.Record, error)
 }
 
 type service struct {
@@ -35,3 +37,17 @@ func (service *service) GetRecord(id int64) (*record.Record, error) {
 
 	return rec, nil
 }
+
+// GetRecordAsOf method for read record by id as it was at given time
+func (service *service) GetRecordAsOf(id int64, asOf time.Time) (*record.Record, error) {
+	rec, err := service.record.GetRecordAsOf(id, asOf)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if rec == nil {
+		return nil, tools.RecordNotFound
+	}
+
+	return rec, nil
+}
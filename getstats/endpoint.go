@@ -0,0 +1,30 @@
+package getstats
+
+import (
+	"encoding/json"
+	"interviewtest/tools"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MakeGetStatsEndpoint function create GET endpoint for statistics of file storage
+func MakeGetStatsEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		stats, err := service.GetStats()
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(response).Encode(stats); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Get stats was successful %+v", stats)
+	}
+}
@@ -0,0 +1,68 @@
+package getstats
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tmpStorageFilePath = "/tmp/stats_records.bin"
+
+func TestGetStatsSuccessful(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 4; i++ {
+		rec := record.Record{
+			IntValue:  int64(i),
+			StrValue:  "foo",
+			BoolValue: false,
+			TimeValue: &testingTime,
+		}
+
+		if _, err := fileStorageService.CreateRecord(&rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.DeleteRecord(2); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/admin/stats", nil)
+
+	rr := httptest.NewRecorder()
+
+	handler := MakeGetStatsEndpoint(service)
+	handler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response record.Stats
+
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, record.Stats{Records: 3, DeletedRecords: 1, CorruptRecords: 0}, response)
+}
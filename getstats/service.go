@@ -0,0 +1,33 @@
+package getstats
+
+import (
+	"interviewtest/record"
+
+	"github.com/pkg/errors"
+)
+
+// Service interface provides method for reading statistics of file storage
+type Service interface {
+	GetStats() (*record.Stats, error)
+}
+
+type service struct {
+	record record.MaintenanceStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage maintenance
+func NewService(record record.MaintenanceStorage) Service {
+	return &service{record: record}
+}
+
+// GetStats method for read counts of live, deleted and corrupted records
+func (service *service) GetStats() (*record.Stats, error) {
+	stats, err := service.record.Stats()
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,126 @@
+package importrecords
+
+import (
+	"encoding/json"
+	"interviewtest/tools"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	formatParam  = "format"
+	dryRunParam  = "dryRun"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+// MakePostImportRecordsEndpoint function create POST endpoint for import of records from NDJSON or CSV body
+// format is chosen by query parameter format or by Content-Type header, records are not written with parameter dryRun=true
+// response contains summary of import with line numbers of rejected rows
+func MakePostImportRecordsEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		params := request.URL.Query()
+
+		dryRun, err := parseDryRun(params.Get(dryRunParam))
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		format, err := importFormat(params.Get(formatParam), request.Header.Get("Content-Type"))
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		if format == "" {
+			tools.SetErrResponseWithStatusCode(response, errors.New("import is available from application/x-ndjson or text/csv"), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var rows rowReader
+
+		if format == formatCSV {
+			rows, err = newCSVReader(request.Body)
+		} else {
+			rows = newNDJSONReader(request.Body)
+		}
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		importRes, err := service.Import(rows, dryRun)
+		statusCode := http.StatusOK
+
+		if err != nil {
+			log.Errorf("Import of records stopped: %s", err)
+
+			importRes.ErrText = err.Error()
+			statusCode = tools.ErrStatusCode(err)
+
+			if errors.Is(err, ErrInvalidBody) {
+				statusCode = http.StatusBadRequest
+			}
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+		response.WriteHeader(statusCode)
+
+		if err = json.NewEncoder(response).Encode(importRes); err != nil {
+			log.Errorf("Response of import can not be written: %s", err)
+			return
+		}
+
+		log.Debugf("Import of %d records was finished, %d rows rejected", importRes.Imported, importRes.Rejected)
+	}
+}
+
+// parseDryRun function parses dry run parameter, import is not dry run without the parameter
+func parseDryRun(param string) (bool, error) {
+	if param == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(param)
+
+	if err != nil {
+		return false, tools.FieldErrors{{Field: dryRunParam, Message: "dryRun must be true or false"}}
+	}
+
+	return dryRun, nil
+}
+
+// importFormat function returns format given by parameter or by content type of body
+// empty format is returned for unsupported content type
+func importFormat(param, contentType string) (string, error) {
+	switch param {
+	case formatNDJSON, formatCSV:
+		return param, nil
+	case "":
+	default:
+		return "", tools.FieldErrors{{Field: formatParam, Message: "format must be ndjson or csv"}}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return "", nil
+	}
+
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson":
+		return formatNDJSON, nil
+	case "text/csv":
+		return formatCSV, nil
+	}
+
+	return "", nil
+}
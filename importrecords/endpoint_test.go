@@ -0,0 +1,153 @@
+package importrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportRecords(t *testing.T) {
+	const storageFilePath = "/tmp/import_records.bin"
+
+	const maxStrLength = 10
+
+	fileStorageService, err := storage.NewService(storageFilePath, storage.WithMaxStrLength(maxStrLength))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, maxStrLength)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 1, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		url              string
+		contentType      string
+		body             string
+		expectedCode     int
+		expectedImported int
+		expectedLines    []int
+	}{{
+		name:        "Dry run of NDJSON - expected summary without created records",
+		url:         "/records/import?dryRun=true",
+		contentType: "application/x-ndjson",
+		body: `{"IntValue":2,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+{"IntValue":3,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+{"id":1,"IntValue":4,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+{"id":4,"IntValue":5,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+{"id":4,"IntValue":6,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+{"IntValue":7,"StrValue":"too long value","TimeValue":"2023-12-31T12:42:59Z"}
+`,
+		expectedCode:     http.StatusOK,
+		expectedImported: 3,
+		expectedLines:    []int{3, 5, 6},
+	}, {
+		name:        "Import of NDJSON with invalid lines - expected rejected line numbers",
+		url:         "/records/import",
+		contentType: "application/x-ndjson; charset=utf-8",
+		body: `{"IntValue":2,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+
+{"IntValue":3,"StrValue":""}
+{"IntValue":4,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z","Unknown":1}
+{"id":1,"IntValue":5,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+{"id":5,"IntValue":6,"StrValue":"bar","TimeValue":"2023-12-31T12:42:59Z"}
+`,
+		expectedCode:     http.StatusOK,
+		expectedImported: 2,
+		expectedLines:    []int{3, 4, 5},
+	}, {
+		name:        "Import of CSV with format parameter - expected rejected line numbers",
+		url:         "/records/import?format=csv",
+		contentType: "text/plain",
+		body: `StrValue,IntValue,TimeValue,BoolValue
+"multi
+line",7,2023-12-31T12:42:59Z,true
+baz,foo,2023-12-31T12:42:59Z,false
+baz,8
+baz,9,2023-12-31T12:42:59.987654321Z,
+`,
+		expectedCode:     http.StatusOK,
+		expectedImported: 2,
+		expectedLines:    []int{4, 5},
+	}, {
+		name:         "Import of CSV with invalid header - expected status code 400",
+		url:          "/records/import",
+		contentType:  "text/csv",
+		body:         "IntValue,Foo\n1,2\n",
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Import with invalid dry run - expected status code 400",
+		url:          "/records/import?dryRun=maybe",
+		contentType:  "text/csv",
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Import of unsupported content type - expected status code 415",
+		url:          "/records/import",
+		contentType:  "application/xml",
+		body:         "<records/>",
+		expectedCode: http.StatusUnsupportedMediaType,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/import", MakePostImportRecordsEndpoint(service)).Methods(http.MethodPost)
+
+			req, _ := http.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var importRes importResponse
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&importRes))
+
+			lines := make([]int, 0, len(importRes.RejectedLines))
+
+			for _, rejected := range importRes.RejectedLines {
+				lines = append(lines, rejected.Line)
+			}
+
+			assert.Equal(t, tt.expectedImported, importRes.Imported)
+			assert.Equal(t, tt.expectedLines, lines)
+		})
+	}
+
+	// records of dry run are not created, records without id are created after the last record
+	expected := map[int64]int64{1: 1, 2: 2, 5: 6, 6: 7, 7: 9}
+
+	for id, intValue := range expected {
+		rec, err := fileStorageService.GetRecord(id)
+		assert.NoError(t, err)
+		assert.Equal(t, intValue, rec.IntValue)
+	}
+
+	rec, err := fileStorageService.GetRecord(3)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+}
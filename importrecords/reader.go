@@ -0,0 +1,205 @@
+package importrecords
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"interviewtest/query"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxLineLength is maximal length of one line of NDJSON body in bytes
+const maxLineLength = 1 << 20
+
+// ErrInvalidBody error of body which can not be read, rows after the error are not imported
+var ErrInvalidBody = errors.New("body of import can not be read")
+
+// row imported record with line number in body, err is set for row which can not be parsed
+type row struct {
+	line int
+	rec  *record.Record
+	err  error
+}
+
+// rowReader reads rows of imported body, io.EOF is returned after the last row
+type rowReader interface {
+	next() (*row, error)
+}
+
+// ndjsonReader reads rows of body with one JSON record per line, empty lines are skipped
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// newNDJSONReader function creates reader of NDJSON body
+func newNDJSONReader(body io.Reader) rowReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	return &ndjsonReader{scanner: scanner}
+}
+
+// next method reads next non-empty line as record
+func (reader *ndjsonReader) next() (*row, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+		line := bytes.TrimSpace(reader.scanner.Bytes())
+
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec record.Record
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+
+		err := decoder.Decode(&rec)
+
+		if err == nil && decoder.More() {
+			err = errors.New("line contains more than one record")
+		}
+
+		return &row{line: reader.line, rec: &rec, err: err}, nil
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return nil, errors.Wrapf(ErrInvalidBody, "line %d: %s", reader.line+1, err)
+	}
+
+	return nil, io.EOF
+}
+
+// csvReader reads rows of CSV body, columns are given by header in the first line
+// column id is optional, record without id is created with the next free id
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+var (
+	// csvColumns columns allowed in header of CSV body
+	csvColumns = map[string]bool{
+		query.FieldID:        true,
+		query.FieldIntValue:  true,
+		query.FieldStrValue:  true,
+		query.FieldBoolValue: true,
+		query.FieldTimeValue: true,
+	}
+	// requiredCSVColumns columns which must be in header of CSV body
+	requiredCSVColumns = []string{query.FieldIntValue, query.FieldStrValue, query.FieldTimeValue}
+)
+
+// newCSVReader function creates reader of CSV body and reads its header
+// tools.FieldErrors is returned for missing or invalid header
+func newCSVReader(body io.Reader) (rowReader, error) {
+	reader := csv.NewReader(body)
+
+	header, err := reader.Read()
+
+	if err == io.EOF {
+		return nil, tools.FieldErrors{{Field: "header", Message: "CSV header is missing"}}
+	} else if err != nil {
+		return nil, tools.FieldErrors{{Field: "header", Message: err.Error()}}
+	}
+
+	var fieldErrors tools.FieldErrors
+	found := make(map[string]bool, len(header))
+
+	for _, column := range header {
+		if !csvColumns[column] {
+			fieldErrors = append(fieldErrors, tools.FieldError{Field: "header", Message: fmt.Sprintf("unknown column %q", column)})
+		} else if found[column] {
+			fieldErrors = append(fieldErrors, tools.FieldError{Field: "header", Message: fmt.Sprintf("duplicate column %q", column)})
+		}
+
+		found[column] = true
+	}
+
+	for _, column := range requiredCSVColumns {
+		if !found[column] {
+			fieldErrors = append(fieldErrors, tools.FieldError{Field: "header", Message: fmt.Sprintf("missing column %q", column)})
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+
+	return &csvReader{reader: reader, columns: header}, nil
+}
+
+// next method reads next row of CSV as record, malformed row is returned with error
+func (reader *csvReader) next() (*row, error) {
+	values, err := reader.reader.Read()
+
+	var parseError *csv.ParseError
+
+	if errors.As(err, &parseError) {
+		return &row{line: parseError.StartLine, err: parseError.Err}, nil
+	} else if err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, errors.Wrap(ErrInvalidBody, err.Error())
+	}
+
+	line, _ := reader.reader.FieldPos(0)
+	rec, err := reader.parseRecord(values)
+
+	return &row{line: line, rec: rec, err: err}, nil
+}
+
+// parseRecord method parses values of columns into record, tools.FieldErrors with invalid values is returned
+func (reader *csvReader) parseRecord(values []string) (*record.Record, error) {
+	var (
+		rec         record.Record
+		fieldErrors tools.FieldErrors
+		err         error
+	)
+
+	for i, column := range reader.columns {
+		value := values[i]
+
+		switch column {
+		case query.FieldID:
+			if value != "" {
+				rec.Id, err = strconv.ParseInt(value, 10, 64)
+			}
+		case query.FieldIntValue:
+			rec.IntValue, err = strconv.ParseInt(value, 10, 64)
+		case query.FieldStrValue:
+			rec.StrValue = value
+		case query.FieldBoolValue:
+			if value != "" {
+				rec.BoolValue, err = strconv.ParseBool(value)
+			}
+		case query.FieldTimeValue:
+			if value != "" {
+				var timeValue time.Time
+
+				if timeValue, err = time.Parse(time.RFC3339Nano, value); err == nil {
+					rec.TimeValue = &timeValue
+				}
+			}
+		}
+
+		if err != nil {
+			fieldErrors = append(fieldErrors, tools.FieldError{Field: column, Message: fmt.Sprintf("invalid value %q", value)})
+			err = nil
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+
+	return &rec, nil
+}
@@ -0,0 +1,233 @@
+package importrecords
+
+import (
+	"interviewtest/createrecord"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// batchSize is maximal number of records created in one storage transaction
+	batchSize = 1000
+	// maxRejectedLines is maximal number of rejected lines listed in response, all rejected lines are counted
+	maxRejectedLines = 1000
+)
+
+// Service interface provides method for import of records into file storage
+type Service interface {
+	Import(rows rowReader, dryRun bool) (*importResponse, error)
+}
+
+type service struct {
+	record       record.Storage
+	maxStrLength int
+}
+
+// NewService constructor of service
+// Arguments are interface of storage and maximal length of StrValue accepted by storage, it is checked by dry run
+func NewService(record record.Storage, maxStrLength int) Service {
+	return &service{record: record, maxStrLength: maxStrLength}
+}
+
+// importResponse structure of response with summary of import
+// records are not written by dry run, imported is number of records which would be created
+type importResponse struct {
+	ErrText       string         `json:"errText,omitempty"`
+	DryRun        bool           `json:"dryRun"`
+	Rows          int            `json:"rows"`
+	Imported      int            `json:"imported"`
+	Rejected      int            `json:"rejected"`
+	RejectedLines []rejectedLine `json:"rejectedLines"`
+}
+
+// rejectedLine structure with line number and error of rejected row
+type rejectedLine struct {
+	Line  int                 `json:"line"`
+	Error tools.ErrorResponse `json:"error"`
+}
+
+// Import method validates rows and creates valid records in storage transactions of batchSize records
+// rows are read before transaction is started, so storage is not locked while body is received
+// invalid rows and rows refused by storage are rejected, import stops at error of reading or writing
+// summary is returned with the error, batches written before the error stay in storage
+// dry run checks rows by reads of storage without transaction, storage is not changed and not locked
+func (service *service) Import(rows rowReader, dryRun bool) (*importResponse, error) {
+	res := &importResponse{DryRun: dryRun, RejectedLines: []rejectedLine{}}
+	batch := make([]*row, 0, batchSize)
+
+	write := service.write
+
+	if dryRun {
+		checkedIDs := make(map[int64]bool)
+
+		write = func(batch []*row, res *importResponse) error {
+			return service.check(batch, checkedIDs, res)
+		}
+	}
+
+	for {
+		r, err := rows.next()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			// rows before the unreadable one are written
+			if err := write(batch, res); err != nil {
+				return res, err
+			}
+
+			return res, errors.WithStack(err)
+		}
+
+		res.Rows++
+
+		if r.err == nil {
+			r.err = validateRecord(r.rec)
+		}
+
+		if r.err != nil {
+			res.reject(r.line, r.err)
+			continue
+		}
+
+		batch = append(batch, r)
+
+		if len(batch) == batchSize {
+			if err := write(batch, res); err != nil {
+				return res, err
+			}
+
+			batch = batch[:0]
+		}
+	}
+
+	if err := write(batch, res); err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// write method creates records of batch in one transaction
+// record refused by storage is rejected, other records of batch are created
+func (service *service) write(batch []*row, res *importResponse) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	tx, err := service.record.Begin()
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	created := 0
+
+	for _, r := range batch {
+		if r.rec.Id != 0 {
+			err = tx.CreateRecordAt(r.rec.Id, r.rec)
+		} else {
+			_, err = tx.CreateRecord(r.rec)
+		}
+
+		// error which is not caused by the record stops import
+		if err != nil && !rejectedByStorage(err) {
+			tx.Rollback()
+			return errors.WithStack(err)
+		}
+
+		if err != nil {
+			res.reject(r.line, err)
+			continue
+		}
+
+		created++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	res.Imported += created
+
+	return nil
+}
+
+// rejectedByStorage function returns true for error of record which can not be created, other records are imported
+func rejectedByStorage(err error) bool {
+	return errors.Is(err, record.ErrStrValueTooLong) || errors.Is(err, record.ErrIDOutOfRange) || errors.Is(err, record.ErrRecordExists)
+}
+
+// check method checks records of batch as they would be created by storage, nothing is written
+// record longer than storage limit and record with id of existing record or of record checked before are rejected
+// id far after end of storage file is not detected, such record is refused by storage when it is imported
+func (service *service) check(batch []*row, checkedIDs map[int64]bool, res *importResponse) error {
+	for _, r := range batch {
+		if len(r.rec.StrValue) > service.maxStrLength {
+			res.reject(r.line, errors.Wrapf(record.ErrStrValueTooLong, "length %d bytes exceeds limit %d bytes", len(r.rec.StrValue), service.maxStrLength))
+			continue
+		}
+
+		if r.rec.Id < 0 {
+			res.reject(r.line, errors.Wrapf(record.ErrIDOutOfRange, "id %d", r.rec.Id))
+			continue
+		}
+
+		if r.rec.Id > 0 {
+			exists := checkedIDs[r.rec.Id]
+
+			if !exists {
+				rec, err := service.record.GetRecord(r.rec.Id)
+
+				// slot of corrupted record is not free
+				if err != nil && !errors.Is(err, record.ErrCorruptRecord) {
+					return errors.WithStack(err)
+				}
+
+				exists = rec != nil || err != nil
+			}
+
+			if exists {
+				res.reject(r.line, errors.Wrapf(record.ErrRecordExists, "id %d", r.rec.Id))
+				continue
+			}
+
+			checkedIDs[r.rec.Id] = true
+		}
+
+		res.Imported++
+	}
+
+	return nil
+}
+
+// reject method counts rejected row, error of row is listed until maxRejectedLines is reached
+func (res *importResponse) reject(line int, err error) {
+	res.Rejected++
+
+	if len(res.RejectedLines) == maxRejectedLines {
+		return
+	}
+
+	rejected := rejectedLine{Line: line, Error: tools.ErrorResponse{ErrText: err.Error()}}
+
+	var fieldErrors tools.FieldErrors
+
+	if errors.As(err, &fieldErrors) {
+		rejected.Error.Fields = fieldErrors
+	}
+
+	res.RejectedLines = append(res.RejectedLines, rejected)
+}
+
+// validateRecord function validates record with validator of created records
+func validateRecord(rec *record.Record) error {
+	if err := createrecord.Validate(rec); err != nil {
+		return tools.FieldErrorsFromValidation(err, "")
+	}
+
+	return nil
+}
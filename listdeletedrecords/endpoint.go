@@ -0,0 +1,47 @@
+package listdeletedrecords
+
+import (
+	"encoding/json"
+	"interviewtest/pagination"
+	"interviewtest/tools"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MakeGetDeletedRecordsEndpoint function create GET endpoint for list of deleted records which can be restored
+// page of records is defined by query parameters limit and cursor
+func MakeGetDeletedRecordsEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		params := request.URL.Query()
+
+		limit, err := pagination.ParseLimit(params)
+
+		if err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		deletedRes, err := service.ListDeleted(params.Get(pagination.CursorParam), limit)
+
+		if err != nil && (errors.Is(err, pagination.ErrInvalidCursor) || errors.Is(err, pagination.ErrInvalidLimit)) {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(response).Encode(deletedRes); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("List of %d deleted records was successful", len(deletedRes.Items))
+	}
+}
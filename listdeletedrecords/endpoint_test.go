@@ -0,0 +1,120 @@
+package listdeletedrecords
+
+import (
+	"encoding/json"
+	"interviewtest/pagination"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDeletedRecords(t *testing.T) {
+	const storageFilePath = "/tmp/list_deleted_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath, storage.WithDeletedRetention(time.Hour))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 4; i++ {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, id := range []int64{1, 3, 4} {
+		if _, err := fileStorageService.DeleteRecord(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedCode       int
+		expectedIDs        []int64
+		expectedNextCursor bool
+	}{{
+		name:         "List all deleted records - expected status code 200",
+		expectedCode: http.StatusOK,
+		expectedIDs:  []int64{1, 3, 4},
+	}, {
+		name:               "List first page - expected status code 200",
+		query:              "?limit=2",
+		expectedCode:       http.StatusOK,
+		expectedIDs:        []int64{1, 3},
+		expectedNextCursor: true,
+	}, {
+		name:         "List next page - expected status code 200",
+		query:        "?limit=2&cursor=" + pagination.EncodeCursor(&pagination.Position{ID: 3}),
+		expectedCode: http.StatusOK,
+		expectedIDs:  []int64{4},
+	}, {
+		name:         "List with invalid limit - expected status code 400",
+		query:        "?limit=0",
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "List with invalid cursor - expected status code 400",
+		query:        "?cursor=x",
+		expectedCode: http.StatusBadRequest,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/deleted", MakeGetDeletedRecordsEndpoint(service)).Methods(http.MethodGet)
+
+			req, _ := http.NewRequest("GET", "/records/deleted"+tt.query, nil)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var deletedRes struct {
+				Items []struct {
+					ID         int64     `json:"id"`
+					IntValue   int64     `json:"IntValue"`
+					DeletedAt  time.Time `json:"deletedAt"`
+					ReusableAt time.Time `json:"reusableAt"`
+				} `json:"items"`
+				NextCursor string `json:"nextCursor"`
+			}
+
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&deletedRes))
+
+			ids := make([]int64, 0, len(deletedRes.Items))
+
+			for _, item := range deletedRes.Items {
+				ids = append(ids, item.ID)
+				assert.Equal(t, item.ID, item.IntValue)
+				assert.WithinDuration(t, time.Now(), item.DeletedAt, time.Minute)
+				assert.Equal(t, item.DeletedAt.Add(time.Hour), item.ReusableAt)
+			}
+
+			assert.Equal(t, tt.expectedIDs, ids)
+			assert.Equal(t, tt.expectedNextCursor, deletedRes.NextCursor != "")
+		})
+	}
+}
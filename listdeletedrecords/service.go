@@ -0,0 +1,63 @@
+package listdeletedrecords
+
+import (
+	"interviewtest/pagination"
+	"interviewtest/record"
+
+	"github.com/pkg/errors"
+)
+
+// Service interface provides method for listing deleted records which can be restored
+type Service interface {
+	ListDeleted(cursor string, limit int) (*deletedResponse, error)
+}
+
+type service struct {
+	record record.ReadingStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage
+func NewService(record record.Storage) Service {
+	return &service{record: record}
+}
+
+// ListDeleted method returns page of deleted records following the cursor in order of id
+// empty cursor starts at the first record, cursor of next page is empty for the last page
+func (service *service) ListDeleted(cursor string, limit int) (*deletedResponse, error) {
+	if err := pagination.CheckLimit(limit); err != nil {
+		return nil, err
+	}
+
+	var position pagination.Position
+
+	if err := pagination.DecodeCursor(cursor, &position); err != nil {
+		return nil, err
+	}
+
+	items := make([]*record.DeletedRecord, 0, limit+1)
+
+	// one more record is read to find out whether next page exists
+	err := service.record.ScanDeletedRecords(position.ID, func(deleted *record.DeletedRecord) bool {
+		items = append(items, deleted)
+		return len(items) <= limit
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &deletedResponse{Items: items}
+
+	if len(items) > limit {
+		res.Items = items[:limit]
+		res.NextCursor = pagination.EncodeCursor(&pagination.Position{ID: items[limit-1].Id})
+	}
+
+	return res, nil
+}
+
+type deletedResponse struct {
+	Items      []*record.DeletedRecord `json:"items"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
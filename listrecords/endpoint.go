@@ -0,0 +1,61 @@
+package listrecords
+
+import (
+	"encoding/json"
+	"interviewtest/pagination"
+	"interviewtest/query"
+	"interviewtest/tools"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MakeGetListRecordsEndpoint function create GET endpoint for list of records
+// page of records is defined by query parameters limit and cursor
+// other query parameters are filters and sort order of records
+func MakeGetListRecordsEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		params := request.URL.Query()
+
+		limit, err := pagination.ParseLimit(params)
+
+		if err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		cursor := params.Get(pagination.CursorParam)
+
+		params.Del(pagination.LimitParam)
+		params.Del(pagination.CursorParam)
+
+		q, err := query.Parse(params)
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		listRes, err := service.ListRecords(q, cursor, limit)
+
+		if err != nil && (errors.Is(err, pagination.ErrInvalidCursor) || errors.Is(err, pagination.ErrInvalidLimit) || errors.Is(err, ErrTooManySorted)) {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(response).Encode(listRes); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("List of %d records was successful", len(listRes.Items))
+	}
+}
@@ -0,0 +1,520 @@
+package listrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tmpStorageFilePath = "/tmp/list_records.bin"
+
+type testListResponse struct {
+	Items      []record.Record `json:"items"`
+	NextCursor string          `json:"nextCursor"`
+}
+
+func TestListRecordsPagination(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 100)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 5; i++ {
+		rec := record.Record{
+			IntValue:  int64(i),
+			StrValue:  "foo",
+			BoolValue: false,
+			TimeValue: &testingTime,
+		}
+
+		if _, err := fileStorageService.CreateRecord(&rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.DeleteRecord(2); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := MakeGetListRecordsEndpoint(service)
+
+	var ids []int64
+	url := "/records?limit=2"
+	pages := 0
+
+	for url != "" {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var response testListResponse
+		if err = json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.LessOrEqual(t, len(response.Items), 2)
+
+		for _, rec := range response.Items {
+			ids = append(ids, rec.Id)
+		}
+
+		url = ""
+		pages++
+
+		if response.NextCursor != "" {
+			url = "/records?limit=2&cursor=" + response.NextCursor
+		}
+	}
+
+	assert.Equal(t, []int64{1, 3, 4, 5}, ids)
+	assert.Equal(t, 2, pages)
+}
+
+func TestListRecordsEmptyStorage(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 100)
+
+	req, err := http.NewRequest("GET", "/records", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler := MakeGetListRecordsEndpoint(service)
+	handler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"items":[]}`, rr.Body.String())
+}
+
+func TestListRecordsBadRequest(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 100)
+
+	tests := []struct {
+		name string
+		url  string
+	}{{
+		name: "Limit is not a number",
+		url:  "/records?limit=foo",
+	}, {
+		name: "Limit is zero",
+		url:  "/records?limit=0",
+	}, {
+		name: "Limit is greater than maximum",
+		url:  "/records?limit=1001",
+	}, {
+		name: "Cursor is not base64",
+		url:  "/records?cursor=%21%21",
+	}, {
+		name: "Cursor does not contain id",
+		url:  "/records?cursor=Zm9v",
+	}, {
+		name: "Cursor of other sort order",
+		url:  "/records?sort=IntValue&cursor=eyJpZCI6MX0",
+	}}
+
+	for _, tst := range tests {
+		tt := tst
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := MakeGetListRecordsEndpoint(service)
+			handler(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
+
+func TestListRecordsFilterAndSort(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 100)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i, intValue := range []int64{50, 10, 40, 20, 30, 60} {
+		rec := record.Record{
+			IntValue:  intValue,
+			StrValue:  "foo",
+			BoolValue: i%2 == 0,
+			TimeValue: &testingTime,
+		}
+
+		if _, err := fileStorageService.CreateRecord(&rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		expectedIDs []int64
+	}{{
+		name:        "Filter by IntValue range",
+		url:         "/records?IntValue.gte=20&IntValue.lte=50",
+		expectedIDs: []int64{1, 3, 4, 5},
+	}, {
+		name:        "Filter by IntValue range with pagination",
+		url:         "/records?IntValue.gte=20&IntValue.lte=50&limit=3",
+		expectedIDs: []int64{1, 3, 4, 5},
+	}, {
+		name:        "Sort by IntValue with pagination",
+		url:         "/records?sort=IntValue&limit=4",
+		expectedIDs: []int64{2, 4, 5, 3, 1, 6},
+	}, {
+		name:        "Filter by BoolValue and sort by IntValue in descending order with pagination",
+		url:         "/records?BoolValue=true&sort=-IntValue&limit=1",
+		expectedIDs: []int64{1, 3, 5},
+	}, {
+		name:        "No record matches",
+		url:         "/records?StrValue.prefix=bar",
+		expectedIDs: nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int64
+			url := tt.url
+
+			for url != "" {
+				req, err := http.NewRequest("GET", url, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				rr := httptest.NewRecorder()
+
+				handler := MakeGetListRecordsEndpoint(service)
+				handler(rr, req)
+
+				assert.Equal(t, http.StatusOK, rr.Code)
+
+				var response testListResponse
+				if err = json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, rec := range response.Items {
+					ids = append(ids, rec.Id)
+				}
+
+				url = ""
+
+				if response.NextCursor != "" {
+					url = tt.url + "&cursor=" + response.NextCursor
+				}
+			}
+
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestListRecordsInvalidFilter(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 100)
+
+	req, err := http.NewRequest("GET", "/records?IntValue.gte=foo&sort=Foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler := MakeGetListRecordsEndpoint(service)
+	handler(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response tools.ErrorResponse
+	if err = json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, response.Fields, 2)
+	assert.Equal(t, "IntValue.gte", response.Fields[0].Field)
+	assert.Equal(t, "sort", response.Fields[1].Field)
+}
+
+func TestListRecordsWithIndex(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath, storage.WithIndexes("IntValue", "TimeValue"))
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 100)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i, intValue := range []int64{50, 10, 40, 20, 30, 60} {
+		timeValue := testingTime.Add(time.Duration(i) * time.Hour)
+
+		rec := record.Record{
+			IntValue:  intValue,
+			StrValue:  "foo",
+			BoolValue: i%2 == 0,
+			TimeValue: &timeValue,
+		}
+
+		if _, err := fileStorageService.CreateRecord(&rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.DeleteRecord(3); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		expectedIDs []int64
+	}{{
+		name:        "Filter by IntValue range with pagination",
+		url:         "/records?IntValue.gt=10&IntValue.lte=50&limit=2",
+		expectedIDs: []int64{1, 4, 5},
+	}, {
+		name:        "Filter by TimeValue range and BoolValue",
+		url:         "/records?TimeValue.gte=2023-12-31T13:42:59Z&BoolValue=true",
+		expectedIDs: []int64{5},
+	}, {
+		name:        "Filter by IntValue range and BoolValue with pagination",
+		url:         "/records?IntValue.gte=10&BoolValue=true&limit=1",
+		expectedIDs: []int64{1, 5},
+	}, {
+		name:        "Sort by TimeValue in descending order with pagination",
+		url:         "/records?sort=-TimeValue&limit=2",
+		expectedIDs: []int64{6, 5, 4, 2, 1},
+	}, {
+		name:        "Filter by IntValue range and sort by IntValue with pagination",
+		url:         "/records?IntValue.gte=20&sort=-IntValue&limit=2",
+		expectedIDs: []int64{6, 1, 5, 4},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int64
+			url := tt.url
+
+			for url != "" {
+				req, err := http.NewRequest("GET", url, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				rr := httptest.NewRecorder()
+
+				handler := MakeGetListRecordsEndpoint(service)
+				handler(rr, req)
+
+				assert.Equal(t, http.StatusOK, rr.Code)
+
+				var response testListResponse
+				if err = json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, rec := range response.Items {
+					ids = append(ids, rec.Id)
+				}
+
+				url = ""
+
+				if response.NextCursor != "" {
+					url = tt.url + "&cursor=" + response.NextCursor
+				}
+			}
+
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestListRecordsSortedByLongStrValue(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 100)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+	prefix := strings.Repeat("ž", 1000)
+
+	for _, suffix := range []string{"c", "a", "d", "b"} {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 1, StrValue: prefix + suffix, TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []int64
+	url := "/records?sort=StrValue&limit=1"
+
+	for url != "" {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+
+		handler := MakeGetListRecordsEndpoint(service)
+		handler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var response testListResponse
+		if err = json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, rec := range response.Items {
+			ids = append(ids, rec.Id)
+		}
+
+		// cursor holds beginning of StrValue only
+		assert.Less(t, len(response.NextCursor), 300)
+
+		url = ""
+
+		if response.NextCursor != "" {
+			url = "/records?sort=StrValue&limit=1&cursor=" + response.NextCursor
+		}
+	}
+
+	assert.Equal(t, []int64{2, 4, 1, 3}, ids)
+}
+
+func TestListRecordsTooManySorted(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath, storage.WithIndexes("IntValue"))
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, 2)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for _, intValue := range []int64{30, 10, 20} {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: intValue, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+	}{
+		{name: "Sort by field without index", url: "/records?sort=StrValue", expectedCode: http.StatusBadRequest},
+		{name: "Sort of filtered records by field without index", url: "/records?sort=StrValue&IntValue.gte=20", expectedCode: http.StatusOK},
+		{name: "Sort by indexed field", url: "/records?sort=-IntValue", expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := MakeGetListRecordsEndpoint(service)
+			handler(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package listrecords
+
+import (
+	"interviewtest/pagination"
+	"interviewtest/query"
+	"interviewtest/record"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// maxCursorStrLength maximal length of StrValue of last record of page stored in cursor in bytes
+const maxCursorStrLength = 64
+
+// ErrTooManySorted error of sort by field without index of more records than can be sorted in memory
+var ErrTooManySorted = errors.New("too many records for sort by field without index")
+
+// Service interface provides method for listing records from file storage
+type Service interface {
+	ListRecords(q *query.Query, cursor string, limit int) (*listResponse, error)
+}
+
+type service struct {
+	record           record.ReadingStorage
+	maxSortedRecords int
+}
+
+// NewService constructor of service
+// Arguments are interface of storage and maximal number of matching records sorted in memory by field without index
+func NewService(record record.Storage, maxSortedRecords int) Service {
+	return &service{record: record, maxSortedRecords: maxSortedRecords}
+}
+
+// ListRecords method returns page of records matching the query following the cursor
+// empty cursor starts at the first record, cursor of next page is empty for the last page
+// records sorted by indexed field are read in order of secondary index, other sorted records are sorted in memory
+func (service *service) ListRecords(q *query.Query, cursor string, limit int) (*listResponse, error) {
+	if err := pagination.CheckLimit(limit); err != nil {
+		return nil, err
+	}
+
+	position, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor != "" && position.Sort != q.SortOrder() {
+		return nil, errors.Wrap(pagination.ErrInvalidCursor, "cursor was returned for different sort order")
+	}
+
+	if err := service.fullSortKey(position); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var items []*record.Record
+
+	if q.Sorted() {
+		items, err = service.sortedRecords(q, position, limit)
+	} else {
+		items, err = service.scanRecords(q, position, limit)
+	}
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &listResponse{Items: items}
+
+	// one more record is read to find out whether next page exists
+	if len(items) > limit {
+		res.Items = items[:limit]
+		res.NextCursor = pagination.EncodeCursor(newCursorPosition(q, items[limit-1]))
+	}
+
+	return res, nil
+}
+
+// scanRecords method reads matching records in order of id following the cursor
+func (service *service) scanRecords(q *query.Query, position *cursorPosition, limit int) ([]*record.Record, error) {
+	items := make([]*record.Record, 0, limit+1)
+
+	err := service.forEachRecord(q, position.ID, limit+1, func(rec *record.Record) bool {
+		if q.Match(rec) {
+			items = append(items, rec)
+		}
+
+		return len(items) <= limit
+	})
+
+	return items, errors.WithStack(err)
+}
+
+// sortedRecords method returns records following the cursor in sort order
+// records are read in order of secondary index of sort field, records sorted by field without index are sorted in memory
+func (service *service) sortedRecords(q *query.Query, position *cursorPosition, limit int) ([]*record.Record, error) {
+	if valueRange, ok := q.SortRange(); ok {
+		items, err := service.indexSortedRecords(q, valueRange, position, limit)
+
+		if !errors.Is(err, record.ErrNotIndexed) {
+			return items, errors.WithStack(err)
+		}
+	}
+
+	return service.memorySortedRecords(q, position, limit)
+}
+
+// indexSortedRecords method reads matching records following the cursor in order of secondary index of sort field
+// entries of index are read in chunks of page size, so page costs about one page of reads for most filters
+func (service *service) indexSortedRecords(q *query.Query, valueRange record.Range, position *cursorPosition, limit int) ([]*record.Record, error) {
+	var after *record.IndexEntry
+
+	if position.Key != nil {
+		after = &record.IndexEntry{Key: q.SortIndexKey(position.Key), ID: position.ID}
+	}
+
+	items := make([]*record.Record, 0, limit+1)
+
+	for {
+		entries, err := service.record.LookupOrdered(valueRange, after, q.Descending(), limit+1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, entry := range entries {
+			rec, err := service.record.GetRecord(entry.ID)
+
+			// record changed after lookup is skipped, it is listed at position of its new value
+			if err != nil && !errors.Is(err, record.ErrCorruptRecord) {
+				return nil, errors.WithStack(err)
+			} else if rec == nil || q.SortIndexKey(rec) != entry.Key || !q.Match(rec) {
+				continue
+			}
+
+			if items = append(items, rec); len(items) > limit {
+				return items, nil
+			}
+		}
+
+		if len(entries) <= limit {
+			return items, nil
+		}
+
+		after = &entries[len(entries)-1]
+	}
+}
+
+// memorySortedRecords method reads all matching records, sorts them and returns records following the cursor
+// ErrTooManySorted is returned when more than maxSortedRecords records match the query
+func (service *service) memorySortedRecords(q *query.Query, position *cursorPosition, limit int) ([]*record.Record, error) {
+	items := make([]*record.Record, 0)
+	matched := 0
+
+	err := service.forEachRecord(q, 0, pagination.MaxLimit, func(rec *record.Record) bool {
+		if !q.Match(rec) {
+			return true
+		}
+
+		if matched++; position.Key == nil || q.Compare(rec, position.Key) > 0 {
+			items = append(items, rec)
+		}
+
+		return matched <= service.maxSortedRecords
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if matched > service.maxSortedRecords {
+		return nil, errors.Wrapf(ErrTooManySorted, "at most %d matching records can be sorted, filter records or sort by indexed field", service.maxSortedRecords)
+	}
+
+	q.Sort(items)
+
+	if len(items) > limit+1 {
+		items = items[:limit+1]
+	}
+
+	return items, nil
+}
+
+// forEachRecord method iterates records with id greater than afterID in order of id
+// records are looked up by secondary index in chunks of ids when range of indexed field is filtered,
+// otherwise whole storage is scanned, chunk is number of records expected to be read
+func (service *service) forEachRecord(q *query.Query, afterID int64, chunk int, fn func(rec *record.Record) bool) error {
+	for _, valueRange := range q.Ranges() {
+		ids, err := service.record.LookupRangeAfter(valueRange, afterID, chunk)
+
+		if errors.Is(err, record.ErrNotIndexed) {
+			continue
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for {
+			for _, id := range ids {
+				rec, err := service.record.GetRecord(id)
+
+				// corrupted record is skipped as in scan, record could be deleted after lookup
+				if err != nil && !errors.Is(err, record.ErrCorruptRecord) {
+					return errors.WithStack(err)
+				} else if rec == nil {
+					continue
+				}
+
+				if !fn(rec) {
+					return nil
+				}
+			}
+
+			if len(ids) < chunk {
+				return nil
+			}
+
+			if ids, err = service.record.LookupRangeAfter(valueRange, ids[len(ids)-1], chunk); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+
+	return errors.WithStack(service.record.ScanRecords(afterID, fn))
+}
+
+// cursorPosition structure with last record of previous page
+// Truncated is true when StrValue of Key is cut to maxCursorStrLength bytes
+type cursorPosition struct {
+	pagination.Position
+	Sort      string         `json:"sort,omitempty"`
+	Key       *record.Record `json:"key,omitempty"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+// newCursorPosition function returns position of last record of page with sort key of query
+// long StrValue is cut, so size of cursor does not depend on length of StrValue
+func newCursorPosition(q *query.Query, last *record.Record) *cursorPosition {
+	position := &cursorPosition{Position: pagination.Position{ID: last.Id}, Sort: q.SortOrder(), Key: q.SortKey(last)}
+
+	if len(position.Key.StrValue) > maxCursorStrLength {
+		cut := maxCursorStrLength
+
+		// StrValue is cut at the beginning of character to stay valid UTF-8 in JSON
+		for cut > 0 && !utf8.RuneStart(position.Key.StrValue[cut]) {
+			cut--
+		}
+
+		position.Key.StrValue = position.Key.StrValue[:cut]
+		position.Truncated = true
+	}
+
+	return position
+}
+
+// fullSortKey method replaces StrValue cut in cursor by StrValue of last record of previous page
+// cut value is kept when the record was changed, records with the same beginning of StrValue can be listed again
+func (service *service) fullSortKey(position *cursorPosition) error {
+	if !position.Truncated {
+		return nil
+	}
+
+	rec, err := service.record.GetRecord(position.ID)
+
+	if err != nil && !errors.Is(err, record.ErrCorruptRecord) {
+		return errors.WithStack(err)
+	}
+
+	if rec != nil && strings.HasPrefix(rec.StrValue, position.Key.StrValue) {
+		position.Key.StrValue = rec.StrValue
+	}
+
+	return nil
+}
+
+// decodeCursor function decodes last record of previous page from cursor
+func decodeCursor(cursor string) (*cursorPosition, error) {
+	var position cursorPosition
+
+	if err := pagination.DecodeCursor(cursor, &position); err != nil {
+		return nil, err
+	}
+
+	// key of sorted page is required for comparison with records
+	if (position.Sort != "" || position.Truncated) && position.Key == nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+
+	return &position, nil
+}
+
+type listResponse struct {
+	Items      []*record.Record `json:"items"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
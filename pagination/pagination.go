@@ -0,0 +1,91 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLimit number of records returned when limit is not defined
+	DefaultLimit = 100
+	// MaxLimit maximal number of records returned by one request
+	MaxLimit = 1000
+	// LimitParam name of query parameter with maximal number of returned records
+	LimitParam = "limit"
+	// CursorParam name of query parameter with cursor of next page
+	CursorParam = "cursor"
+)
+
+var (
+	// ErrInvalidCursor error of cursor which was not returned by list of records
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidLimit error of limit out of allowed range
+	ErrInvalidLimit = errors.Errorf("limit must be between 1 and %d", MaxLimit)
+)
+
+// Position structure with id of last record of page, every cursor contains it
+type Position struct {
+	ID int64 `json:"id"`
+}
+
+// ParseLimit function returns limit from query parameters, DefaultLimit is returned when limit is not defined
+// ErrInvalidLimit is returned for limit which is not a number or is out of allowed range
+func ParseLimit(params url.Values) (int, error) {
+	limitValue := params.Get(LimitParam)
+
+	if limitValue == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitValue)
+	if err != nil {
+		return 0, ErrInvalidLimit
+	}
+
+	return limit, CheckLimit(limit)
+}
+
+// CheckLimit function returns ErrInvalidLimit for limit out of allowed range
+func CheckLimit(limit int) error {
+	if limit < 1 || limit > MaxLimit {
+		return ErrInvalidLimit
+	}
+
+	return nil
+}
+
+// EncodeCursor function encodes position of last record of page into opaque cursor
+func EncodeCursor(position interface{}) string {
+	positionBytes, _ := json.Marshal(position)
+
+	return base64.RawURLEncoding.EncodeToString(positionBytes)
+}
+
+// DecodeCursor function decodes position of last record of previous page from cursor into position
+// position is not changed by empty cursor, ErrInvalidCursor is returned for cursor without id of record
+func DecodeCursor(cursor string, position interface{}) error {
+	if cursor == "" {
+		return nil
+	}
+
+	positionBytes, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	var last Position
+
+	if err := json.Unmarshal(positionBytes, &last); err != nil || last.ID < 1 {
+		return ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(positionBytes, position); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
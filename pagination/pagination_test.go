@@ -0,0 +1,68 @@
+package pagination
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		rawQuery      string
+		expected      int
+		expectedError error
+	}{
+		{name: "Default limit", rawQuery: "", expected: DefaultLimit},
+		{name: "Limit", rawQuery: "limit=10", expected: 10},
+		{name: "Maximal limit", rawQuery: "limit=1000", expected: MaxLimit},
+		{name: "Limit is not a number", rawQuery: "limit=foo", expectedError: ErrInvalidLimit},
+		{name: "Zero limit", rawQuery: "limit=0", expectedError: ErrInvalidLimit},
+		{name: "Limit above maximum", rawQuery: "limit=1001", expectedError: ErrInvalidLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.rawQuery)
+			assert.NoError(t, err)
+
+			limit, err := ParseLimit(values)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	type sortedPosition struct {
+		Position
+		Sort string `json:"sort"`
+	}
+
+	cursor := EncodeCursor(&sortedPosition{Position: Position{ID: 42}, Sort: "-IntValue"})
+
+	var position sortedPosition
+
+	assert.NoError(t, DecodeCursor(cursor, &position))
+	assert.Equal(t, sortedPosition{Position: Position{ID: 42}, Sort: "-IntValue"}, position)
+
+	// cursor of other list has the same id
+	var last Position
+
+	assert.NoError(t, DecodeCursor(cursor, &last))
+	assert.Equal(t, int64(42), last.ID)
+
+	assert.NoError(t, DecodeCursor("", &last))
+	assert.Equal(t, int64(42), last.ID)
+
+	for _, invalid := range []string{"not base64!", EncodeCursor("foo"), EncodeCursor(&Position{})} {
+		assert.ErrorIs(t, DecodeCursor(invalid, &last), ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,63 @@
+package patchrecord
+
+import (
+	"encoding/json"
+	"interviewtest/tools"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MakePatchRecordEndpoint function create PATCH endpoint for partial modification of record
+// patch document is JSON Merge Patch or JSON Patch given by Content-Type header, updated record is returned
+// patch is applied only when If-Match header matches ETag of stored record, otherwise 412 is returned
+func MakePatchRecordEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		recID := mux.Vars(request)["id"]
+
+		id, err := strconv.ParseInt(recID, 10, 64)
+
+		if err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		patch, err := io.ReadAll(request.Body)
+
+		if err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		rec, err := service.Patch(id, request.Header.Get("Content-Type"), patch, request.Header.Get("If-Match"))
+
+		switch {
+		case errors.Is(err, ErrUnsupportedPatch):
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusUnsupportedMediaType)
+			return
+		case errors.Is(err, ErrInvalidPatch):
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		case errors.Is(err, ErrPatchConflict):
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusConflict)
+			return
+		case err != nil:
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+		response.Header().Set("ETag", tools.ETag(rec.Version))
+
+		if err = json.NewEncoder(response).Encode(rec); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Patch record %s was successful", recID)
+	}
+}
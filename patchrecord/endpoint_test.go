@@ -0,0 +1,219 @@
+package patchrecord
+
+import (
+	"encoding/json"
+	"fmt"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+const tmpStorageFilePath = "/tmp/patch_records.bin"
+
+func TestPatchRecord(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		contentType  string
+		patch        string
+		ifMatch      string
+		idURLParam   int64
+		expectedCode int
+		expectedData record.Record
+	}{{
+		name:         "Merge patch of IntValue and BoolValue - expected status code 200",
+		contentType:  MergePatchContentType,
+		patch:        `{"IntValue": 142, "BoolValue": true}`,
+		idURLParam:   1,
+		expectedCode: http.StatusOK,
+		expectedData: record.Record{Id: 1, IntValue: 142, StrValue: "foo", BoolValue: true, TimeValue: &testingTime},
+	}, {
+		name:         "Merge patch with id in body - expected status code 200",
+		contentType:  MergePatchContentType + "; charset=utf-8",
+		patch:        `{"id": 7, "StrValue": "bar"}`,
+		idURLParam:   1,
+		expectedCode: http.StatusOK,
+		expectedData: record.Record{Id: 1, IntValue: 42, StrValue: "bar", TimeValue: &testingTime},
+	}, {
+		name:         "Merge patch removing required field - expected status code 400",
+		contentType:  MergePatchContentType,
+		patch:        `{"StrValue": null}`,
+		idURLParam:   1,
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Merge patch with invalid TimeValue - expected status code 400",
+		contentType:  MergePatchContentType,
+		patch:        `{"TimeValue": "yesterday"}`,
+		idURLParam:   1,
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Merge patch with unknown field - expected status code 400",
+		contentType:  MergePatchContentType,
+		patch:        `{"Unknown": 1}`,
+		idURLParam:   1,
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Malformed merge patch - expected status code 400",
+		contentType:  MergePatchContentType,
+		patch:        `{"IntValue": `,
+		idURLParam:   1,
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "JSON patch with test and replace - expected status code 200",
+		contentType:  JSONPatchContentType,
+		patch:        `[{"op": "test", "path": "/IntValue", "value": 42}, {"op": "replace", "path": "/IntValue", "value": 43}, {"op": "replace", "path": "/TimeValue", "value": "2024-01-01T00:00:00Z"}]`,
+		idURLParam:   1,
+		expectedCode: http.StatusOK,
+		expectedData: record.Record{Id: 1, IntValue: 43, StrValue: "foo", TimeValue: timePointer(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))},
+	}, {
+		name:         "JSON patch with failed test - expected status code 409",
+		contentType:  JSONPatchContentType,
+		patch:        `[{"op": "test", "path": "/IntValue", "value": 41}, {"op": "replace", "path": "/IntValue", "value": 43}]`,
+		idURLParam:   1,
+		expectedCode: http.StatusConflict,
+	}, {
+		name:         "JSON patch replacing missing field - expected status code 409",
+		contentType:  JSONPatchContentType,
+		patch:        `[{"op": "replace", "path": "/Missing", "value": 43}]`,
+		idURLParam:   1,
+		expectedCode: http.StatusConflict,
+	}, {
+		name:         "JSON patch with unknown operation - expected status code 400",
+		contentType:  JSONPatchContentType,
+		patch:        `[{"op": "increment", "path": "/IntValue", "value": 1}]`,
+		idURLParam:   1,
+		expectedCode: http.StatusBadRequest,
+	}, {
+		name:         "Patch with unsupported content type - expected status code 415",
+		contentType:  "application/json",
+		patch:        `{"IntValue": 142}`,
+		idURLParam:   1,
+		expectedCode: http.StatusUnsupportedMediaType,
+	}, {
+		name:         "Patch with old ETag - expected status code 412",
+		contentType:  MergePatchContentType,
+		patch:        `{"IntValue": 142}`,
+		ifMatch:      `"1"`,
+		idURLParam:   1,
+		expectedCode: http.StatusPreconditionFailed,
+	}, {
+		name:         "Patch of not existing record - expected status code 404",
+		contentType:  MergePatchContentType,
+		patch:        `{"IntValue": 142}`,
+		idURLParam:   99,
+		expectedCode: http.StatusNotFound,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// every test patches the same original record
+			if _, err := fileStorageService.EditRecord(1, &record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+				t.Fatal(err)
+			}
+
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}", MakePatchRecordEndpoint(service)).Methods(http.MethodPatch)
+
+			req, _ := http.NewRequest("PATCH", fmt.Sprintf("/records/%d", tt.idURLParam), strings.NewReader(tt.patch))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("If-Match", tt.ifMatch)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			persistedData, err := fileStorageService.GetRecord(1)
+			assert.NoError(t, err)
+
+			if tt.expectedCode != http.StatusOK {
+				// record is not changed by failed patch
+				assert.Equal(t, int64(42), persistedData.IntValue)
+				assert.Equal(t, "foo", persistedData.StrValue)
+				return
+			}
+
+			var responseData record.Record
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&responseData))
+
+			assert.Equal(t, fmt.Sprintf(`"%d"`, persistedData.Version), rr.Header().Get("ETag"))
+
+			for _, rec := range []*record.Record{&responseData, persistedData} {
+				assert.Equal(t, tt.expectedData.Id, rec.Id)
+				assert.Equal(t, tt.expectedData.IntValue, rec.IntValue)
+				assert.Equal(t, tt.expectedData.StrValue, rec.StrValue)
+				assert.Equal(t, tt.expectedData.BoolValue, rec.BoolValue)
+				assert.True(t, tt.expectedData.TimeValue.Equal(*rec.TimeValue))
+			}
+		})
+	}
+}
+
+func TestPatchRecordFieldErrors(t *testing.T) {
+	const storageFilePath = "/tmp/patch_field_errors_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.Handle("/records/{id:[0-9]+}", MakePatchRecordEndpoint(NewService(fileStorageService))).Methods(http.MethodPatch)
+
+	req, _ := http.NewRequest("PATCH", "/records/1", strings.NewReader(`[{"op": "remove", "path": "/StrValue"}, {"op": "remove", "path": "/TimeValue"}]`))
+	req.Header.Set("Content-Type", JSONPatchContentType)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{
+		"errText": "invalid fields: StrValue: field failed on required validation, TimeValue: field failed on required validation",
+		"fields": [
+			{"field": "StrValue", "message": "field failed on required validation"},
+			{"field": "TimeValue", "message": "field failed on required validation"}
+		]
+	}`, rr.Body.String())
+}
+
+func timePointer(t time.Time) *time.Time {
+	return &t
+}
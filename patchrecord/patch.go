@@ -0,0 +1,386 @@
+package patchrecord
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// patcher applies patch document to JSON document decoded into maps, slices and values
+type patcher interface {
+	apply(document interface{}) (interface{}, error)
+}
+
+// mergePatch JSON Merge Patch, members of patch replace members of document, null removes member
+type mergePatch struct {
+	patch interface{}
+}
+
+func (patch mergePatch) apply(document interface{}) (interface{}, error) {
+	return mergeValue(document, patch.patch), nil
+}
+
+// mergeValue function merges patch into target, patch which is not object replaces target
+func mergeValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+
+		targetObject[name] = mergeValue(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// jsonPatch JSON Patch, sequence of operations applied in order, all operations must succeed
+type jsonPatch []operation
+
+// operation of JSON Patch, Value is nil when operation has no value
+type operation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// validate method verifies operations before patch is applied
+func (patch jsonPatch) validate() error {
+	for i, op := range patch {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return errors.Wrapf(ErrInvalidPatch, "operation %d: %s requires value", i, op.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return errors.Wrapf(ErrInvalidPatch, "operation %d: %s", i, err)
+			}
+		case "remove":
+		default:
+			return errors.Wrapf(ErrInvalidPatch, "operation %d: unknown operation %q", i, op.Op)
+		}
+
+		if _, err := parsePointer(op.Path); err != nil {
+			return errors.Wrapf(ErrInvalidPatch, "operation %d: %s", i, err)
+		}
+
+		if op.Op == "move" && strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+			return errors.Wrapf(ErrInvalidPatch, "operation %d: value can not be moved into itself", i)
+		}
+	}
+
+	return nil
+}
+
+func (patch jsonPatch) apply(document interface{}) (interface{}, error) {
+	for i, op := range patch {
+		var err error
+
+		if document, err = op.apply(document); err != nil {
+			return nil, errors.Wrapf(err, "operation %d", i)
+		}
+	}
+
+	return document, nil
+}
+
+// apply method applies operation to document, pointers are validated before
+func (op operation) apply(document interface{}) (interface{}, error) {
+	path, _ := parsePointer(op.Path)
+
+	var value interface{}
+
+	if op.Value != nil {
+		if err := decodeJSON(*op.Value, &value); err != nil {
+			return nil, errors.Wrap(ErrInvalidPatch, err.Error())
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return add(document, path, value)
+	case "remove":
+		return remove(document, path)
+	case "replace":
+		if _, err := get(document, path); err != nil {
+			return nil, err
+		}
+
+		// whole document is replaced by value
+		if len(path) == 0 {
+			return value, nil
+		}
+
+		document, err := remove(document, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return add(document, path, value)
+	case "move", "copy":
+		from, _ := parsePointer(op.From)
+
+		value, err := get(document, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if document, err = remove(document, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = copyValue(value)
+		}
+
+		return add(document, path, value)
+	default:
+		actual, err := get(document, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !equalValues(actual, value) {
+			return nil, errors.Wrapf(ErrPatchConflict, "value of %s is different", op.Path)
+		}
+
+		return document, nil
+	}
+}
+
+// parsePointer function splits JSON pointer into reference tokens, empty pointer refers whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.Errorf("pointer %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// get function returns value referenced by pointer tokens
+func get(document interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := document.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, errors.Wrapf(ErrPatchConflict, "member %q does not exist", token)
+			}
+
+			document = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			document = container[index]
+		default:
+			return nil, errors.Wrapf(ErrPatchConflict, "value at %q is not object or array", token)
+		}
+	}
+
+	return document, nil
+}
+
+// add function adds value into object or inserts value into array at pointer
+func add(document interface{}, path []string, value interface{}) (interface{}, error) {
+	return update(document, path, value, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			return container, nil
+		case []interface{}:
+			index := len(container)
+
+			if token != "-" {
+				var err error
+
+				if index, err = arrayIndex(token, len(container)); err != nil {
+					return nil, err
+				}
+			}
+
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+
+			return container, nil
+		default:
+			return nil, errors.Wrapf(ErrPatchConflict, "value at %q is not object or array", token)
+		}
+	})
+}
+
+// remove function removes member of object or item of array at pointer
+func remove(document interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.Wrap(ErrPatchConflict, "whole document can not be removed")
+	}
+
+	return update(document, path, nil, func(container interface{}, token string) (interface{}, error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			if _, ok := container[token]; !ok {
+				return nil, errors.Wrapf(ErrPatchConflict, "member %q does not exist", token)
+			}
+
+			delete(container, token)
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(container[:index], container[index+1:]...), nil
+		default:
+			return nil, errors.Wrapf(ErrPatchConflict, "value at %q is not object or array", token)
+		}
+	})
+}
+
+// update function changes container referenced by all pointer tokens except the last one
+// changed container is set back into its parent, value replaces document for empty pointer
+func update(document interface{}, path []string, value interface{}, change func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	if len(path) == 1 {
+		return change(document, path[0])
+	}
+
+	child, err := get(document, path[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	if child, err = update(child, path[1:], value, change); err != nil {
+		return nil, err
+	}
+
+	switch container := document.(type) {
+	case map[string]interface{}:
+		container[path[0]] = child
+	case []interface{}:
+		index, _ := arrayIndex(path[0], len(container)-1)
+		container[index] = child
+	}
+
+	return document, nil
+}
+
+// arrayIndex function parses array index of pointer token, index must not be greater than max
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.Wrapf(ErrInvalidPatch, "invalid array index %q", token)
+	}
+
+	if index > max {
+		return 0, errors.Wrapf(ErrPatchConflict, "array index %d is out of range", index)
+	}
+
+	return index, nil
+}
+
+// copyValue function returns deep copy of JSON value
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+
+		for name, item := range value {
+			copied[name] = copyValue(item)
+		}
+
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+
+		for i, item := range value {
+			copied[i] = copyValue(item)
+		}
+
+		return copied
+	default:
+		return value
+	}
+}
+
+// equalValues function compares JSON values, numbers are compared by value
+func equalValues(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for name, item := range a {
+			if other, ok := b[name]; !ok || !equalValues(item, other) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if !equalValues(a[i], b[i]) {
+				return false
+			}
+		}
+
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		aInt, aErr := a.Int64()
+		bInt, bErr := b.Int64()
+
+		if aErr == nil && bErr == nil {
+			return aInt == bInt
+		}
+
+		aFloat, aErr := a.Float64()
+		bFloat, bErr := b.Float64()
+
+		return aErr == nil && bErr == nil && aFloat == bFloat
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,159 @@
+package patchrecord
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		expected string
+	}{{
+		name:     "Replace member",
+		document: `{"a": "b"}`,
+		patch:    `{"a": "c"}`,
+		expected: `{"a": "c"}`,
+	}, {
+		name:     "Remove member",
+		document: `{"a": "b", "c": 1}`,
+		patch:    `{"a": null}`,
+		expected: `{"c": 1}`,
+	}, {
+		name:     "Merge nested object",
+		document: `{"a": {"b": 1, "c": 2}}`,
+		patch:    `{"a": {"c": null, "d": 3}}`,
+		expected: `{"a": {"b": 1, "d": 3}}`,
+	}, {
+		name:     "Replace array",
+		document: `{"a": [1, 2]}`,
+		patch:    `{"a": [3]}`,
+		expected: `{"a": [3]}`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher, err := parsePatch(MergePatchContentType, []byte(tt.patch))
+			assert.NoError(t, err)
+
+			assert.JSONEq(t, tt.expected, applyToJSON(t, patcher, tt.document))
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		expected string
+		err      error
+	}{{
+		name:     "Add member and array item",
+		document: `{"a": [1, 3]}`,
+		patch:    `[{"op": "add", "path": "/b", "value": true}, {"op": "add", "path": "/a/1", "value": 2}, {"op": "add", "path": "/a/-", "value": 4}]`,
+		expected: `{"a": [1, 2, 3, 4], "b": true}`,
+	}, {
+		name:     "Remove array item",
+		document: `{"a": [1, 2, 3]}`,
+		patch:    `[{"op": "remove", "path": "/a/0"}]`,
+		expected: `{"a": [2, 3]}`,
+	}, {
+		name:     "Move and copy member",
+		document: `{"a": {"b": 1}, "c": 2}`,
+		patch:    `[{"op": "move", "from": "/c", "path": "/a/c"}, {"op": "copy", "from": "/a", "path": "/d"}]`,
+		expected: `{"a": {"b": 1, "c": 2}, "d": {"b": 1, "c": 2}}`,
+	}, {
+		name:     "Escaped pointer",
+		document: `{"a/b": 1, "m~n": 2}`,
+		patch:    `[{"op": "replace", "path": "/a~1b", "value": 3}, {"op": "test", "path": "/m~0n", "value": 2.0}]`,
+		expected: `{"a/b": 3, "m~n": 2}`,
+	}, {
+		name:     "Test of different value",
+		document: `{"a": "b"}`,
+		patch:    `[{"op": "test", "path": "/a", "value": "c"}]`,
+		err:      ErrPatchConflict,
+	}, {
+		name:     "Remove missing member",
+		document: `{"a": "b"}`,
+		patch:    `[{"op": "remove", "path": "/b"}]`,
+		err:      ErrPatchConflict,
+	}, {
+		name:     "Add item after end of array",
+		document: `{"a": [1]}`,
+		patch:    `[{"op": "add", "path": "/a/2", "value": 2}]`,
+		err:      ErrPatchConflict,
+	}, {
+		name:     "Invalid array index",
+		document: `{"a": [1]}`,
+		patch:    `[{"op": "add", "path": "/a/01", "value": 2}]`,
+		err:      ErrInvalidPatch,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patcher, err := parsePatch(JSONPatchContentType, []byte(tt.patch))
+			assert.NoError(t, err)
+
+			var document interface{}
+			assert.NoError(t, decodeJSON([]byte(tt.document), &document))
+
+			patched, err := patcher.apply(document)
+
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			data, err := json.Marshal(patched)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestParseJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{{
+		name:  "Operation without value",
+		patch: `[{"op": "add", "path": "/a"}]`,
+	}, {
+		name:  "Pointer without leading slash",
+		patch: `[{"op": "remove", "path": "a"}]`,
+	}, {
+		name:  "Move into itself",
+		patch: `[{"op": "move", "from": "/a", "path": "/a/b"}]`,
+	}, {
+		name:  "Patch is not array",
+		patch: `{"op": "remove", "path": "/a"}`,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePatch(JSONPatchContentType, []byte(tt.patch))
+			assert.ErrorIs(t, err, ErrInvalidPatch)
+		})
+	}
+}
+
+func applyToJSON(t *testing.T, patcher patcher, document string) string {
+	var decoded interface{}
+	assert.NoError(t, decodeJSON([]byte(document), &decoded))
+
+	patched, err := patcher.apply(decoded)
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(patched)
+	assert.NoError(t, err)
+
+	return string(data)
+}
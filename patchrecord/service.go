@@ -0,0 +1,165 @@
+package patchrecord
+
+import (
+	"bytes"
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"mime"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+// Media types of supported patch documents
+const (
+	// MergePatchContentType JSON Merge Patch (RFC 7396)
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType JSON Patch (RFC 6902)
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	// ErrUnsupportedPatch error of patch with unknown content type
+	ErrUnsupportedPatch = errors.New("unsupported patch content type")
+	// ErrInvalidPatch error of malformed patch document or patch which results in invalid record
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrPatchConflict error of patch which can not be applied to current record
+	ErrPatchConflict = errors.New("patch can not be applied to record")
+)
+
+// Service interface provides method for partial modification of record in file storage
+type Service interface {
+	Patch(id int64, contentType string, patch []byte, ifMatch string) (*record.Record, error)
+}
+
+type service struct {
+	record record.ModificationStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage
+func NewService(record record.Storage) Service {
+	return &service{record: record}
+}
+
+// Patch method applies patch document to record and returns updated record
+// record is read, patched, validated and written under storage lock, so concurrent writes are not lost
+// ID of record is always taken from the path, patch is applied only when ifMatch is empty or matches version of record
+func (service *service) Patch(id int64, contentType string, patch []byte, ifMatch string) (*record.Record, error) {
+	patcher, err := parsePatch(contentType, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := service.record.UpdateRecord(id, func(rec *record.Record) error {
+		if err := tools.CheckIfMatch(ifMatch, rec.Version); err != nil {
+			return err
+		}
+
+		return applyPatch(rec, patcher)
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if rec == nil {
+		return nil, tools.RecordNotFound
+	}
+
+	return rec, nil
+}
+
+// parsePatch function parses patch document of given content type
+func parsePatch(contentType string, patch []byte) (patcher, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnsupportedPatch, "%q", contentType)
+	}
+
+	switch mediaType {
+	case MergePatchContentType:
+		var document interface{}
+
+		if err := decodeJSON(patch, &document); err != nil {
+			return nil, errors.Wrap(ErrInvalidPatch, err.Error())
+		}
+
+		return mergePatch{patch: document}, nil
+	case JSONPatchContentType:
+		var operations jsonPatch
+
+		decoder := json.NewDecoder(bytes.NewReader(patch))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&operations); err != nil {
+			return nil, errors.Wrap(ErrInvalidPatch, err.Error())
+		}
+
+		if err := operations.validate(); err != nil {
+			return nil, err
+		}
+
+		return operations, nil
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedPatch, "%q, supported types are %s and %s", mediaType, MergePatchContentType, JSONPatchContentType)
+	}
+}
+
+// applyPatch function applies patch to JSON document of record and validates patched record
+// record is changed only when patched record is valid
+func applyPatch(rec *record.Record, patcher patcher) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var document interface{}
+
+	if err := decodeJSON(data, &document); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if document, err = patcher.apply(document); err != nil {
+		return err
+	}
+
+	if data, err = json.Marshal(document); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var patchedRecord record.Record
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&patchedRecord); err != nil {
+		return errors.Wrapf(ErrInvalidPatch, "patched record: %s", err)
+	}
+
+	validate := validator.New()
+
+	if err := validate.Struct(&patchedRecord); err != nil {
+		return tools.FieldErrorsFromValidation(err, "")
+	}
+
+	*rec = patchedRecord
+	return nil
+}
+
+// decodeJSON function decodes JSON document, numbers are kept as json.Number without loss of precision
+func decodeJSON(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(value); err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return errors.New("unexpected data after JSON document")
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package query
+
+import (
+	"fmt"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of record fields used in filter and sort parameters
+const (
+	FieldID        = "id"
+	FieldIntValue  = "IntValue"
+	FieldStrValue  = "StrValue"
+	FieldBoolValue = "BoolValue"
+	FieldTimeValue = "TimeValue"
+)
+
+// SortParam name of query parameter with sort field, field with prefix '-' is sorted in descending order
+const SortParam = "sort"
+
+// operators allowed for every field, first operator is used when parameter has no operator
+var fieldOperators = map[string][]string{
+	FieldIntValue:  {"eq", "ne", "gt", "gte", "lt", "lte"},
+	FieldStrValue:  {"eq", "ne", "prefix", "contains"},
+	FieldBoolValue: {"eq"},
+	FieldTimeValue: {"eq", "ne", "gt", "gte", "lt", "lte"},
+}
+
+// Query structure with filters and sort order of records
+type Query struct {
+	filters    []filter
+	ranges     map[string]*record.Range
+	sortField  string
+	descending bool
+}
+
+// filter matches record by one condition
+type filter func(rec *record.Record) bool
+
+// Parse function parses filter and sort parameters of list request
+// filter parameter is Field.operator=value, for example IntValue.gte=10 or StrValue.prefix=foo
+// parameter without operator is equality filter, all filters must match
+// tools.FieldErrors with all invalid parameters is returned for invalid query
+func Parse(values url.Values) (*Query, error) {
+	query := Query{ranges: make(map[string]*record.Range)}
+	var fieldErrors tools.FieldErrors
+
+	for param, paramValues := range values {
+		if param == SortParam {
+			if err := query.parseSort(paramValues); err != nil {
+				fieldErrors = append(fieldErrors, tools.FieldError{Field: param, Message: err.Error()})
+			}
+
+			continue
+		}
+
+		for _, value := range paramValues {
+			if err := query.parseFilter(param, value); err != nil {
+				fieldErrors = append(fieldErrors, tools.FieldError{Field: param, Message: err.Error()})
+			}
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		// parameters are iterated in random order
+		sort.SliceStable(fieldErrors, func(i, j int) bool { return fieldErrors[i].Field < fieldErrors[j].Field })
+
+		return nil, fieldErrors
+	}
+
+	return &query, nil
+}
+
+// parseSort method parses sort field and direction
+func (query *Query) parseSort(values []string) error {
+	if len(values) != 1 {
+		return fmt.Errorf("only one sort field is allowed")
+	}
+
+	field := strings.TrimPrefix(values[0], "-")
+
+	if _, ok := fieldOperators[field]; !ok && field != FieldID {
+		return fmt.Errorf("unknown sort field %q", field)
+	}
+
+	query.sortField = field
+	query.descending = strings.HasPrefix(values[0], "-")
+
+	return nil
+}
+
+// parseFilter method parses one filter parameter
+// range of IntValue and TimeValue is narrowed by filter for lookup in secondary index
+func (query *Query) parseFilter(param, value string) error {
+	field, operator, _ := strings.Cut(param, ".")
+
+	operators, ok := fieldOperators[field]
+	if !ok {
+		return fmt.Errorf("unknown filter field %q", field)
+	}
+
+	if operator == "" {
+		operator = operators[0]
+	}
+
+	if !contains(operators, operator) {
+		return fmt.Errorf("unknown operator %q, allowed operators are %s", operator, strings.Join(operators, ", "))
+	}
+
+	var f filter
+
+	switch field {
+	case FieldIntValue:
+		expected, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("value %q is not an integer", value)
+		}
+
+		f = func(rec *record.Record) bool { return compareOrdered(rec.IntValue, expected, operator) }
+		query.narrowRange(field, operator, record.IntKey(expected))
+	case FieldStrValue:
+		f = func(rec *record.Record) bool { return matchString(rec.StrValue, value, operator) }
+	case FieldBoolValue:
+		expected, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("value %q is not a boolean", value)
+		}
+
+		f = func(rec *record.Record) bool { return rec.BoolValue == expected }
+	default:
+		expected, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("value %q is not a time in RFC 3339 format", value)
+		}
+
+		f = func(rec *record.Record) bool {
+			return rec.TimeValue != nil && compareOrdered(compareTime(*rec.TimeValue, expected), 0, operator)
+		}
+		query.narrowRange(field, operator, record.TimeKey(expected))
+	}
+
+	query.filters = append(query.filters, f)
+	return nil
+}
+
+// narrowRange method narrows inclusive range of field values by filter operator
+// range can be wider than filter, records found by range are matched by filters
+func (query *Query) narrowRange(field, operator string, key record.IndexKey) {
+	if operator == "ne" {
+		return
+	}
+
+	valueRange, ok := query.ranges[field]
+	if !ok {
+		valueRange = &record.Range{Field: field}
+		query.ranges[field] = valueRange
+	}
+
+	if operator != "lt" && operator != "lte" && (valueRange.Min == nil || key.Compare(*valueRange.Min) > 0) {
+		valueRange.Min = &key
+	}
+
+	if operator != "gt" && operator != "gte" && (valueRange.Max == nil || key.Compare(*valueRange.Max) < 0) {
+		valueRange.Max = &key
+	}
+}
+
+// Ranges method returns ranges of field values required by filters ordered by field name
+func (query *Query) Ranges() []record.Range {
+	ranges := make([]record.Range, 0, len(query.ranges))
+
+	for _, valueRange := range query.ranges {
+		ranges = append(ranges, *valueRange)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Field < ranges[j].Field })
+
+	return ranges
+}
+
+// Match method returns true when record matches all filters
+func (query *Query) Match(rec *record.Record) bool {
+	for _, f := range query.filters {
+		if !f(rec) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Sorted method returns true when records must be sorted by other field than id
+func (query *Query) Sorted() bool {
+	return query.sortField != "" && !(query.sortField == FieldID && !query.descending)
+}
+
+// SortOrder method returns sort parameter of query, empty for order by id
+func (query *Query) SortOrder() string {
+	if !query.Sorted() {
+		return ""
+	}
+
+	if query.descending {
+		return "-" + query.sortField
+	}
+
+	return query.sortField
+}
+
+// Descending method returns true when records are sorted in descending order
+func (query *Query) Descending() bool {
+	return query.descending
+}
+
+// SortRange method returns range of sort field values required by filters for lookup in secondary index
+// false is returned when sort field can not be indexed
+func (query *Query) SortRange() (record.Range, bool) {
+	if query.sortField != FieldIntValue && query.sortField != FieldTimeValue {
+		return record.Range{}, false
+	}
+
+	if valueRange, ok := query.ranges[query.sortField]; ok {
+		return *valueRange, true
+	}
+
+	return record.Range{Field: query.sortField}, true
+}
+
+// SortIndexKey method returns index key of sort field of record, records ordered by index key and id are sorted by query
+func (query *Query) SortIndexKey(rec *record.Record) record.IndexKey {
+	if query.sortField == FieldTimeValue {
+		return record.TimeKey(timeOf(rec))
+	}
+
+	return record.IntKey(rec.IntValue)
+}
+
+// Compare method compares records by sort field, records with the same value are compared by id
+// result is negative when first record precedes second record
+func (query *Query) Compare(a, b *record.Record) int {
+	result := 0
+
+	switch query.sortField {
+	case FieldIntValue:
+		result = compareValues(a.IntValue, b.IntValue)
+	case FieldStrValue:
+		result = strings.Compare(a.StrValue, b.StrValue)
+	case FieldBoolValue:
+		result = compareValues(boolToInt(a.BoolValue), boolToInt(b.BoolValue))
+	case FieldTimeValue:
+		result = compareTime(timeOf(a), timeOf(b))
+	}
+
+	if result == 0 {
+		result = compareValues(a.Id, b.Id)
+	}
+
+	if query.descending {
+		return -result
+	}
+
+	return result
+}
+
+// SortKey method returns copy of record with id and sort field only
+// key is used for comparison of records with the last record of previous page
+func (query *Query) SortKey(rec *record.Record) *record.Record {
+	key := &record.Record{Id: rec.Id}
+
+	switch query.sortField {
+	case FieldIntValue:
+		key.IntValue = rec.IntValue
+	case FieldStrValue:
+		key.StrValue = rec.StrValue
+	case FieldBoolValue:
+		key.BoolValue = rec.BoolValue
+	case FieldTimeValue:
+		key.TimeValue = rec.TimeValue
+	}
+
+	return key
+}
+
+// Sort method sorts records by sort field of query
+func (query *Query) Sort(records []*record.Record) {
+	sort.Slice(records, func(i, j int) bool { return query.Compare(records[i], records[j]) < 0 })
+}
+
+// compareOrdered function compares value with expected value by operator
+func compareOrdered[T int64 | int](value, expected T, operator string) bool {
+	switch operator {
+	case "eq":
+		return value == expected
+	case "ne":
+		return value != expected
+	case "gt":
+		return value > expected
+	case "gte":
+		return value >= expected
+	case "lt":
+		return value < expected
+	default:
+		return value <= expected
+	}
+}
+
+// matchString function compares string with expected value by operator
+func matchString(value, expected, operator string) bool {
+	switch operator {
+	case "eq":
+		return value == expected
+	case "ne":
+		return value != expected
+	case "prefix":
+		return strings.HasPrefix(value, expected)
+	default:
+		return strings.Contains(value, expected)
+	}
+}
+
+func compareValues(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func timeOf(rec *record.Record) time.Time {
+	if rec.TimeValue == nil {
+		return time.Time{}
+	}
+
+	return *rec.TimeValue
+}
+
+func boolToInt(value bool) int64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
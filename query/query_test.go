@@ -0,0 +1,151 @@
+package query
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	rec := &record.Record{
+		Id:        1,
+		IntValue:  42,
+		StrValue:  "foobar",
+		BoolValue: true,
+		TimeValue: &testingTime,
+	}
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		expected bool
+	}{
+		{name: "Empty query", rawQuery: "", expected: true},
+		{name: "IntValue equality", rawQuery: "IntValue=42", expected: true},
+		{name: "IntValue range", rawQuery: "IntValue.gte=42&IntValue.lt=50", expected: true},
+		{name: "IntValue out of range", rawQuery: "IntValue.gt=42", expected: false},
+		{name: "IntValue not equal", rawQuery: "IntValue.ne=42", expected: false},
+		{name: "StrValue equality", rawQuery: "StrValue=foobar", expected: true},
+		{name: "StrValue prefix", rawQuery: "StrValue.prefix=foo", expected: true},
+		{name: "StrValue contains", rawQuery: "StrValue.contains=oba", expected: true},
+		{name: "StrValue does not contain", rawQuery: "StrValue.contains=baz", expected: false},
+		{name: "BoolValue", rawQuery: "BoolValue=false", expected: false},
+		{name: "TimeValue range", rawQuery: "TimeValue.gte=2023-12-31T00:00:00Z&TimeValue.lt=2024-01-01T00:00:00%2B01:00", expected: true},
+		{name: "TimeValue equality in other zone", rawQuery: "TimeValue=2023-12-31T13:42:59.987654321%2B01:00", expected: true},
+		{name: "TimeValue after", rawQuery: "TimeValue.gt=2023-12-31T12:43:00Z", expected: false},
+		{name: "All filters must match", rawQuery: "IntValue=42&StrValue=foo", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.rawQuery)
+			assert.NoError(t, err)
+
+			query, err := Parse(values)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, query.Match(rec))
+		})
+	}
+}
+
+func TestParseInvalidQuery(t *testing.T) {
+	tests := []struct {
+		name           string
+		rawQuery       string
+		expectedFields []string
+	}{
+		{name: "Unknown field", rawQuery: "Foo=1", expectedFields: []string{"Foo"}},
+		{name: "Unknown operator", rawQuery: "StrValue.gt=foo", expectedFields: []string{"StrValue.gt"}},
+		{name: "Invalid integer", rawQuery: "IntValue.gte=foo", expectedFields: []string{"IntValue.gte"}},
+		{name: "Invalid boolean", rawQuery: "BoolValue=foo", expectedFields: []string{"BoolValue"}},
+		{name: "Invalid time", rawQuery: "TimeValue.lt=2023-12-31", expectedFields: []string{"TimeValue.lt"}},
+		{name: "Unknown sort field", rawQuery: "sort=-Foo", expectedFields: []string{"sort"}},
+		{name: "More sort fields", rawQuery: "sort=IntValue&sort=StrValue", expectedFields: []string{"sort"}},
+		{name: "All invalid fields are reported", rawQuery: "IntValue=foo&BoolValue=foo", expectedFields: []string{"BoolValue", "IntValue"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.rawQuery)
+			assert.NoError(t, err)
+
+			query, err := Parse(values)
+			assert.Nil(t, query)
+
+			var fieldErrors tools.FieldErrors
+			assert.ErrorAs(t, err, &fieldErrors)
+
+			var fields []string
+			for _, fieldError := range fieldErrors {
+				fields = append(fields, fieldError.Field)
+				assert.NotEmpty(t, fieldError.Message)
+			}
+
+			assert.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+	laterTime := testingTime.Add(time.Hour)
+
+	records := []*record.Record{
+		{Id: 1, IntValue: 30, StrValue: "b", BoolValue: true, TimeValue: &laterTime},
+		{Id: 2, IntValue: 10, StrValue: "c", BoolValue: false, TimeValue: &testingTime},
+		{Id: 3, IntValue: 20, StrValue: "a", BoolValue: true, TimeValue: &testingTime},
+	}
+
+	tests := []struct {
+		sort        string
+		expectedIDs []int64
+	}{
+		{sort: "id", expectedIDs: []int64{1, 2, 3}},
+		{sort: "-id", expectedIDs: []int64{3, 2, 1}},
+		{sort: "IntValue", expectedIDs: []int64{2, 3, 1}},
+		{sort: "-IntValue", expectedIDs: []int64{1, 3, 2}},
+		{sort: "StrValue", expectedIDs: []int64{3, 1, 2}},
+		{sort: "BoolValue", expectedIDs: []int64{2, 1, 3}},
+		{sort: "TimeValue", expectedIDs: []int64{2, 3, 1}},
+		{sort: "-TimeValue", expectedIDs: []int64{1, 3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			query, err := Parse(url.Values{SortParam: {tt.sort}})
+			assert.NoError(t, err)
+
+			sorted := append([]*record.Record(nil), records...)
+			query.Sort(sorted)
+
+			var ids []int64
+			for _, rec := range sorted {
+				ids = append(ids, rec.Id)
+			}
+
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestRanges(t *testing.T) {
+	values, err := url.ParseQuery("IntValue.gte=10&IntValue.gt=20&IntValue.lt=50&IntValue.ne=30&TimeValue=2023-12-31T12:42:59Z&StrValue=foo")
+	assert.NoError(t, err)
+
+	query, err := Parse(values)
+	assert.NoError(t, err)
+
+	minInt, maxInt := record.IntKey(20), record.IntKey(50)
+	timeKey := record.TimeKey(time.Date(2023, 12, 31, 12, 42, 59, 0, time.UTC))
+
+	assert.Equal(t, []record.Range{
+		{Field: FieldIntValue, Min: &minInt, Max: &maxInt},
+		{Field: FieldTimeValue, Min: &timeKey, Max: &timeKey},
+	}, query.Ranges())
+}
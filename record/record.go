@@ -1,17 +1,87 @@
 package record
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrCorruptRecord error of record which data are damaged in storage
+	ErrCorruptRecord = errors.New("record is corrupted")
+	// ErrStrValueTooLong error of record with StrValue longer than storage limit
+	ErrStrValueTooLong = errors.New("StrValue is too long")
+	// ErrNotIndexed error of lookup by field without secondary index
+	ErrNotIndexed = errors.New("field is not indexed")
+	// ErrRecordExists error of record created with id of existing record
+	ErrRecordExists = errors.New("record already exists")
+	// ErrIDOutOfRange error of record created with id which can not be stored
+	ErrIDOutOfRange = errors.New("id is out of range")
+	// ErrHistoryDisabled error of reading history of records when history is not enabled
+	ErrHistoryDisabled = errors.New("history of records is not enabled")
+	// ErrRecordNotFound error of operation with record which does not exist
+	ErrRecordNotFound = errors.New("record not found")
+	// ErrIndeterminateWrite error of write which failed after it was logged, data can be partially written to storage
+	ErrIndeterminateWrite = errors.New("write failed after it was logged, outcome of write is indeterminate")
+	// ErrTransactionDone error of operation of transaction which is already committed or rolled back
+	ErrTransactionDone = errors.New("transaction is already committed or rolled back")
+	// ErrIdempotencyDisabled error of record created with idempotency key when idempotency keys are not enabled
+	ErrIdempotencyDisabled = errors.New("idempotency keys are not enabled")
+	// ErrIdempotencyKeyReused error of idempotency key used for record with different data
+	ErrIdempotencyKeyReused = errors.New("idempotency key is used by different request")
+	// ErrSnapshotExists error of snapshot created in directory which already exists
+	ErrSnapshotExists = errors.New("snapshot already exists")
+)
+
+// MaxIdempotencyKeyLength is maximal length of idempotency key of created record in bytes
+const MaxIdempotencyKeyLength = 255
+
+// Types of operations applied by batch
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
 
 // ReadingStorage interface provides methods for reading operations
 type ReadingStorage interface {
 	GetRecord(id int64) (*Record, error)
+	ScanRecords(afterID int64, fn func(rec *Record) bool) error
+	LookupRange(valueRange Range) ([]int64, error)
+	LookupRangeAfter(valueRange Range, afterID int64, limit int) ([]int64, error)
+	LookupOrdered(valueRange Range, after *IndexEntry, descending bool, limit int) ([]IndexEntry, error)
+	SearchRecords(query string, limit int) ([]SearchHit, error)
+	RecordHistory(id int64) ([]Revision, error)
+	GetRecordAsOf(id int64, asOf time.Time) (*Record, error)
+	ScanDeletedRecords(afterID int64, fn func(deleted *DeletedRecord) bool) error
 }
 
 // ModificationStorage interface provides methods for modification operation
 type ModificationStorage interface {
 	CreateRecord(rec *Record) (int64, error)
+	CreateRecordAt(id int64, rec *Record) error
+	CreateRecordIdempotent(key string, rec *Record) (bool, error)
+	EditRecord(id int64, updatedRecord *Record) (int64, error)
+	UpdateRecord(id int64, update func(rec *Record) error) (*Record, error)
+	DeleteRecord(id int64) (bool, error)
+	DeleteRecordIf(id int64, condition func(version int64) error) (bool, error)
+	RestoreRecord(id int64) (*Record, error)
+	ApplyBatch(operations []Operation) error
+	Begin() (Transaction, error)
+}
+
+// Transaction interface provides modification operations applied together by commit
+// writes of transaction are visible to its operations only, readers of storage see them after commit
+type Transaction interface {
+	GetRecord(id int64) (*Record, error)
+	CreateRecord(rec *Record) (int64, error)
+	CreateRecordAt(id int64, rec *Record) error
 	EditRecord(id int64, updatedRecord *Record) (int64, error)
+	UpdateRecord(id int64, update func(rec *Record) error) (*Record, error)
 	DeleteRecord(id int64) (bool, error)
+	DeleteRecordIf(id int64, condition func(version int64) error) (bool, error)
+	Commit() error
+	Rollback()
 }
 
 // Storage interface provides access for reading and modification operation
@@ -20,8 +90,137 @@ type Storage interface {
 	ModificationStorage
 }
 
+// MaintenanceStorage interface provides methods for maintenance of storage
+type MaintenanceStorage interface {
+	Compact() (map[int64]int64, error)
+	Stats() (*Stats, error)
+	CheckIntegrity() ([]IntegrityIssue, error)
+	Snapshot(dir string) (*Snapshot, error)
+}
+
+// Stats structure with statistics of record slots in storage
+type Stats struct {
+	Records        int64 `json:"records"`
+	DeletedRecords int64 `json:"deletedRecords"`
+	CorruptRecords int64 `json:"corruptRecords"`
+}
+
+// IntegrityIssue structure with problem of record slot found by integrity check
+// StoredID is id stored in slot, it differs from ID of slot for misplaced record
+type IntegrityIssue struct {
+	ID       int64  `json:"id"`
+	StoredID int64  `json:"storedId"`
+	Problem  string `json:"problem"`
+}
+
+// Snapshot structure with description of point-in-time copy of storage files in directory Path
+// names of files start with name of storage file, StorageFile is name of copied storage file
+type Snapshot struct {
+	Path        string         `json:"path"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	StorageFile string         `json:"storageFile"`
+	Files       []SnapshotFile `json:"files"`
+}
+
+// SnapshotFile structure with name and size of file copied into snapshot
+type SnapshotFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Revision structure with previous version of record replaced or deleted at ChangedAt
+// Record is nil when record did not exist before the change (record was created)
+type Revision struct {
+	Version   int64     `json:"version,omitempty"`
+	ChangedAt time.Time `json:"changedAt"`
+	Record    *Record   `json:"record,omitempty"`
+}
+
+// DeletedRecord structure with deleted record which can be restored
+// slot of deleted record is not reused by new record before ReusableAt
+type DeletedRecord struct {
+	*Record
+	DeletedAt  time.Time `json:"deletedAt"`
+	ReusableAt time.Time `json:"reusableAt"`
+}
+
+// Operation structure with one change of batch, Record is not used by delete
+// record is created at ID when ID of create is not zero, Condition checks version of stored record before update or delete
+type Operation struct {
+	Type      string
+	ID        int64
+	Record    *Record
+	Condition func(version int64) error
+}
+
+// BatchError error of batch operation which can not be applied, no operation of batch is applied
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+// Error method returns text of error with index of failed operation
+func (batchError *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %s", batchError.Index, batchError.Err)
+}
+
+// Unwrap method returns error of failed operation
+func (batchError *BatchError) Unwrap() error {
+	return batchError.Err
+}
+
+// IndexKey structure with value of indexed field
+// Value is IntValue or unix seconds of TimeValue, Nanos are nanoseconds of TimeValue
+type IndexKey struct {
+	Value int64
+	Nanos int32
+}
+
+// Range structure with inclusive bounds of indexed field, nil bound is not limited
+type Range struct {
+	Field string
+	Min   *IndexKey
+	Max   *IndexKey
+}
+
+// IndexEntry structure with index key of record and its id, entries of index are ordered by key and id
+type IndexEntry struct {
+	Key IndexKey
+	ID  int64
+}
+
+// SearchHit structure with id of record found by full-text search and its relevance
+type SearchHit struct {
+	ID    int64
+	Score float64
+}
+
+// IntKey function returns index key of IntValue
+func IntKey(value int64) IndexKey {
+	return IndexKey{Value: value}
+}
+
+// TimeKey function returns index key of TimeValue
+func TimeKey(value time.Time) IndexKey {
+	return IndexKey{Value: value.Unix(), Nanos: int32(value.Nanosecond())}
+}
+
+// Compare method compares index keys, result is negative when key is lower than other key
+func (key IndexKey) Compare(other IndexKey) int {
+	switch {
+	case key.Value < other.Value || (key.Value == other.Value && key.Nanos < other.Nanos):
+		return -1
+	case key.Value == other.Value && key.Nanos == other.Nanos:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Record structure with data of record, Version is changed by every write of record
 type Record struct {
 	Id        int64      `json:"id"`
+	Version   int64      `json:"-"`
 	IntValue  int64      `json:"IntValue" validate:"required"`
 	StrValue  string     `json:"StrValue" validate:"required"`
 	BoolValue bool       `json:"BoolValue"`
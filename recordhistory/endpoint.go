@@ -0,0 +1,49 @@
+package recordhistory
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MakeGetRecordHistoryEndpoint function create GET endpoint for history of record
+// previous versions of record are returned in order of changes, 501 is returned when history is not enabled
+func MakeGetRecordHistoryEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		recID := mux.Vars(request)["id"]
+
+		id, err := strconv.ParseInt(recID, 10, 64)
+
+		if err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusNotFound)
+			return
+		}
+
+		historyRes, err := service.History(id)
+
+		if err != nil && errors.Is(err, record.ErrHistoryDisabled) {
+			tools.SetErrResponseWithStatusCode(response, record.ErrHistoryDisabled, http.StatusNotImplemented)
+			return
+		}
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(response).Encode(historyRes); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Get history of record %s was successful", recID)
+	}
+}
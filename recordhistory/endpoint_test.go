@@ -0,0 +1,130 @@
+package recordhistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRecordHistory(t *testing.T) {
+	const storageFilePath = "/tmp/history_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath, storage.WithHistory(true))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := fileStorageService.EditRecord(1, &record.Record{IntValue: 43, StrValue: "bar", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fileStorageService.DeleteRecord(2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		idURLParam       int64
+		expectedCode     int
+		expectedVersions []int64
+	}{{
+		name:             "History of edited record - expected status code 200",
+		idURLParam:       int64(1),
+		expectedCode:     http.StatusOK,
+		expectedVersions: []int64{0, 1},
+	}, {
+		name:             "History of deleted record - expected status code 200",
+		idURLParam:       int64(2),
+		expectedCode:     http.StatusOK,
+		expectedVersions: []int64{0, 1},
+	}, {
+		name:         "History of not existing record - expected status code 404",
+		idURLParam:   int64(99),
+		expectedCode: http.StatusNotFound,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}/history", MakeGetRecordHistoryEndpoint(service)).Methods(http.MethodGet)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/records/%d/history", tt.idURLParam), nil)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var historyRes historyResponse
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&historyRes))
+
+			versions := make([]int64, 0, len(historyRes.Items))
+
+			for _, revision := range historyRes.Items {
+				versions = append(versions, revision.Version)
+			}
+
+			assert.Equal(t, tt.expectedVersions, versions)
+
+			// record did not exist before it was created
+			assert.Nil(t, historyRes.Items[0].Record)
+			assert.Equal(t, tt.idURLParam, historyRes.Items[1].Record.Id)
+			assert.Equal(t, int64(42), historyRes.Items[1].Record.IntValue)
+			assert.Equal(t, "foo", historyRes.Items[1].Record.StrValue)
+		})
+	}
+}
+
+func TestGetRecordHistoryDisabled(t *testing.T) {
+	const storageFilePath = "/tmp/history_disabled_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	router := mux.NewRouter()
+	router.Handle("/records/{id:[0-9]+}/history", MakeGetRecordHistoryEndpoint(NewService(fileStorageService))).Methods(http.MethodGet)
+
+	req, _ := http.NewRequest("GET", "/records/1/history", nil)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
@@ -0,0 +1,48 @@
+package recordhistory
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools"
+
+	"github.com/pkg/errors"
+)
+
+// Service interface provides method for reading history of record from file storage
+type Service interface {
+	History(id int64) (*historyResponse, error)
+}
+
+type service struct {
+	record record.ReadingStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage
+func NewService(record record.Storage) Service {
+	return &service{record: record}
+}
+
+// History method returns previous versions of record in order of changes
+// history of deleted record is returned, RecordNotFound is returned when record has no history and does not exist
+func (service *service) History(id int64) (*historyResponse, error) {
+	revisions, err := service.record.RecordHistory(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(revisions) == 0 {
+		rec, err := service.record.GetRecord(id)
+
+		if err != nil && !errors.Is(err, record.ErrCorruptRecord) {
+			return nil, errors.WithStack(err)
+		} else if rec == nil && err == nil {
+			return nil, tools.RecordNotFound
+		}
+	}
+
+	return &historyResponse{Items: revisions}, nil
+}
+
+type historyResponse struct {
+	Items []record.Revision `json:"items"`
+}
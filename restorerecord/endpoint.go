@@ -0,0 +1,50 @@
+package restorerecord
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MakePostRestoreRecordEndpoint function create POST endpoint for restore of deleted record
+// restored record is returned, 409 is returned when record is not deleted
+func MakePostRestoreRecordEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		recID := mux.Vars(request)["id"]
+
+		id, err := strconv.ParseInt(recID, 10, 64)
+
+		if err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusBadRequest)
+			return
+		}
+
+		rec, err := service.Restore(id)
+
+		if err != nil && errors.Is(err, record.ErrRecordExists) {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+		response.Header().Set("ETag", tools.ETag(rec.Version))
+
+		if err = json.NewEncoder(response).Encode(rec); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Restore record %s was successful", recID)
+	}
+}
@@ -0,0 +1,103 @@
+package restorerecord
+
+import (
+	"encoding/json"
+	"fmt"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreRecord(t *testing.T) {
+	const storageFilePath = "/tmp/restore_records.bin"
+
+	fileStorageService, err := storage.NewService(storageFilePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testtools.CleanupFiles(t, storageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fileStorageService.DeleteRecord(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// slot 2 is empty slot skipped by record created after end of file
+	if err := fileStorageService.CreateRecordAt(3, &record.Record{IntValue: 43, StrValue: "bar", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		idURLParam   int64
+		expectedCode int
+	}{{
+		name:         "Restore deleted record - expected status code 200",
+		idURLParam:   int64(1),
+		expectedCode: http.StatusOK,
+	}, {
+		name:         "Restore restored record - expected status code 409",
+		idURLParam:   int64(1),
+		expectedCode: http.StatusConflict,
+	}, {
+		name:         "Restore empty slot - expected status code 404",
+		idURLParam:   int64(2),
+		expectedCode: http.StatusNotFound,
+	}, {
+		name:         "Restore existing record - expected status code 409",
+		idURLParam:   int64(3),
+		expectedCode: http.StatusConflict,
+	}, {
+		name:         "Restore not existing record - expected status code 404",
+		idURLParam:   int64(99),
+		expectedCode: http.StatusNotFound,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			router.Handle("/records/{id:[0-9]+}/restore", MakePostRestoreRecordEndpoint(service)).Methods(http.MethodPost)
+
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/records/%d/restore", tt.idURLParam), nil)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var responseData record.Record
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&responseData))
+			assert.Equal(t, tt.idURLParam, responseData.Id)
+			assert.Equal(t, int64(42), responseData.IntValue)
+			assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+
+			persistedData, err := fileStorageService.GetRecord(tt.idURLParam)
+			assert.NoError(t, err)
+			assert.NotNil(t, persistedData)
+			assert.Equal(t, "foo", persistedData.StrValue)
+		})
+	}
+}
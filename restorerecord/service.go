@@ -0,0 +1,38 @@
+package restorerecord
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools"
+
+	"github.com/pkg/errors"
+)
+
+// Service interface provides method for restore of deleted record in file storage
+type Service interface {
+	Restore(id int64) (*record.Record, error)
+}
+
+type service struct {
+	record record.ModificationStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage
+func NewService(record record.Storage) Service {
+	return &service{record: record}
+}
+
+// Restore method restores deleted record by id and returns restored record
+// RecordNotFound is returned when slot does not hold data of deleted record, record.ErrRecordExists when record is not deleted
+func (service *service) Restore(id int64) (*record.Record, error) {
+	rec, err := service.record.RestoreRecord(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if rec == nil {
+		return nil, tools.RecordNotFound
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,50 @@
+package searchrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MakeGetSearchRecordsEndpoint function create GET endpoint for full-text search of records
+// words are defined by query parameter q, number of records by query parameter limit
+func MakeGetSearchRecordsEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		limit := DefaultLimit
+
+		if limitValue := request.URL.Query().Get("limit"); limitValue != "" {
+			var err error
+
+			if limit, err = strconv.Atoi(limitValue); err != nil {
+				tools.SetErrResponseWithStatusCode(response, tools.FieldErrors{{Field: "limit", Message: "limit is not a number"}}, http.StatusBadRequest)
+				return
+			}
+		}
+
+		searchRes, err := service.Search(request.URL.Query().Get("q"), limit)
+
+		if err != nil && errors.Is(err, record.ErrNotIndexed) {
+			tools.SetErrResponseWithStatusCode(response, errors.New("full-text search is not enabled"), http.StatusNotImplemented)
+			return
+		}
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(response).Encode(searchRes); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Search found %d records", len(searchRes.Items))
+	}
+}
@@ -0,0 +1,190 @@
+package searchrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tmpStorageFilePath = "/tmp/search_records.bin"
+
+type testSearchResponse struct {
+	Items []struct {
+		record.Record
+		Score float64 `json:"score"`
+	} `json:"items"`
+}
+
+func TestSearchRecordsSuccessful(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath, storage.WithFullTextSearch(true))
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for _, strValue := range []string{"foo bar", "foo foo baz", "bar"} {
+		rec := record.Record{
+			IntValue:  42,
+			StrValue:  strValue,
+			BoolValue: false,
+			TimeValue: &testingTime,
+		}
+
+		if _, err := fileStorageService.CreateRecord(&rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		expectedIDs []int64
+	}{{
+		name:        "Search by word",
+		url:         "/records/search?q=foo",
+		expectedIDs: []int64{2, 1},
+	}, {
+		name:        "Search by prefix with limit",
+		url:         "/records/search?q=ba*&limit=2",
+		expectedIDs: []int64{3, 1},
+	}, {
+		name:        "Nothing found",
+		url:         "/records/search?q=qux",
+		expectedIDs: []int64{},
+	}}
+
+	for _, tst := range tests {
+		tt := tst
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := MakeGetSearchRecordsEndpoint(service)
+			handler(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+			var response testSearchResponse
+			if err = json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			ids := make([]int64, 0)
+
+			for _, item := range response.Items {
+				ids = append(ids, item.Id)
+				assert.Greater(t, item.Score, float64(0))
+			}
+
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestSearchRecordsBadRequest(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath, storage.WithFullTextSearch(true))
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedField string
+	}{{
+		name:          "Missing query",
+		url:           "/records/search",
+		expectedField: "q",
+	}, {
+		name:          "Limit is not a number",
+		url:           "/records/search?q=foo&limit=foo",
+		expectedField: "limit",
+	}, {
+		name:          "Limit is greater than maximum",
+		url:           "/records/search?q=foo&limit=" + strconv.Itoa(MaxLimit+1),
+		expectedField: "limit",
+	}}
+
+	for _, tst := range tests {
+		tt := tst
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := MakeGetSearchRecordsEndpoint(service)
+			handler(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			var response tools.ErrorResponse
+			if err = json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, response.Fields, 1)
+			assert.Equal(t, tt.expectedField, response.Fields[0].Field)
+		})
+	}
+}
+
+func TestSearchRecordsNotEnabled(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService)
+
+	req, err := http.NewRequest("GET", "/records/search?q=foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+
+	handler := MakeGetSearchRecordsEndpoint(service)
+	handler(rr, req)
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
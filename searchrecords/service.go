@@ -0,0 +1,80 @@
+package searchrecords
+
+import (
+	"fmt"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLimit number of records returned when limit is not defined
+	DefaultLimit = 20
+	// MaxLimit maximal number of records returned by one request
+	MaxLimit = 1000
+)
+
+// Service interface provides method for full-text search of records in file storage
+type Service interface {
+	Search(query string, limit int) (*searchResponse, error)
+}
+
+type service struct {
+	record record.ReadingStorage
+}
+
+// NewService constructor of service
+// Argument is interface of storage
+func NewService(record record.Storage) Service {
+	return &service{record: record}
+}
+
+// Search method returns records with StrValue containing all words of query ordered by relevance
+func (service *service) Search(query string, limit int) (*searchResponse, error) {
+	var fieldErrors tools.FieldErrors
+
+	if strings.TrimSpace(query) == "" {
+		fieldErrors = append(fieldErrors, tools.FieldError{Field: "q", Message: "query must not be empty"})
+	}
+
+	if limit < 1 || limit > MaxLimit {
+		fieldErrors = append(fieldErrors, tools.FieldError{Field: "limit", Message: fmt.Sprintf("limit must be between 1 and %d", MaxLimit)})
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+
+	hits, err := service.record.SearchRecords(query, limit)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &searchResponse{Items: make([]*searchItem, 0, len(hits))}
+
+	for _, hit := range hits {
+		rec, err := service.record.GetRecord(hit.ID)
+
+		// corrupted record is skipped, record could be deleted after search
+		if err != nil && !errors.Is(err, record.ErrCorruptRecord) {
+			return nil, errors.WithStack(err)
+		} else if rec == nil {
+			continue
+		}
+
+		res.Items = append(res.Items, &searchItem{Record: rec, Score: hit.Score})
+	}
+
+	return res, nil
+}
+
+type searchItem struct {
+	*record.Record
+	Score float64 `json:"score"`
+}
+
+type searchResponse struct {
+	Items []*searchItem `json:"items"`
+}
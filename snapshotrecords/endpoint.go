@@ -0,0 +1,38 @@
+package snapshotrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/tools"
+	"net/http"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MakePostSnapshotEndpoint function create POST endpoint for snapshot of file storage
+func MakePostSnapshotEndpoint(service Service) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		snapshot, err := service.Snapshot()
+
+		if err != nil && errors.Is(err, record.ErrSnapshotExists) {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusConflict)
+			return
+		}
+
+		if err != nil {
+			tools.SetErrResponse(response, err)
+			return
+		}
+
+		response.Header().Set("Content-Type", "application/json")
+		response.WriteHeader(http.StatusCreated)
+
+		if err = json.NewEncoder(response).Encode(snapshot); err != nil {
+			tools.SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Debugf("Snapshot %s was successful", snapshot.Path)
+	}
+}
@@ -0,0 +1,67 @@
+package snapshotrecords
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"interviewtest/storage"
+	"interviewtest/tools/testtools"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	tmpStorageFilePath = "/tmp/snapshot_records.bin"
+	tmpSnapshotDir     = "/tmp/snapshot_records_snapshots"
+)
+
+func TestSnapshotRecordsSuccessful(t *testing.T) {
+	fileStorageService, err := storage.NewService(tmpStorageFilePath)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	testtools.CleanupFiles(t, tmpStorageFilePath)
+	defer t.Cleanup(func() {
+		os.RemoveAll(tmpSnapshotDir)
+		fileStorageService.Close()
+	})
+
+	service := NewService(fileStorageService, tmpSnapshotDir)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	if _, err := fileStorageService.CreateRecord(&record.Record{IntValue: 1, StrValue: "foo", TimeValue: &testingTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("POST", "/admin/snapshot", nil)
+
+	rr := httptest.NewRecorder()
+
+	handler := MakePostSnapshotEndpoint(service)
+	handler(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var snapshot record.Snapshot
+
+	if err := json.NewDecoder(rr.Body).Decode(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, tmpSnapshotDir, filepath.Dir(snapshot.Path))
+	assert.Equal(t, "snapshot_records.bin", snapshot.StorageFile)
+
+	stat, err := os.Stat(filepath.Join(snapshot.Path, snapshot.StorageFile))
+
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Files[0].Size, stat.Size())
+}
@@ -0,0 +1,37 @@
+package snapshotrecords
+
+import (
+	"interviewtest/record"
+	"interviewtest/storage"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Service interface provides method for snapshot of file storage
+type Service interface {
+	Snapshot() (*record.Snapshot, error)
+}
+
+type service struct {
+	record      record.MaintenanceStorage
+	snapshotDir string
+}
+
+// NewService constructor of service
+// Arguments are interface of storage maintenance and directory of snapshots
+func NewService(record record.MaintenanceStorage, snapshotDir string) Service {
+	return &service{record: record, snapshotDir: snapshotDir}
+}
+
+// Snapshot method creates point-in-time copy of file storage in new directory of snapshots
+// Method return description of created snapshot
+func (service *service) Snapshot() (*record.Snapshot, error) {
+	snapshot, err := service.record.Snapshot(storage.SnapshotPath(service.snapshotDir, time.Now()))
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return snapshot, nil
+}
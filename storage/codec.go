@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"interviewtest/record"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Record codec encodes whole record into slot buffer and decodes it back by field offsets
+// slot is read or written by one I/O call, slot buffers are reused from pool
+type slotBuffer [recordSize]byte
+
+var slotPool = sync.Pool{
+	New: func() any { return new(slotBuffer) },
+}
+
+// newSlot function returns slot buffer from pool, buffer must be released after use
+func newSlot() *slotBuffer {
+	return slotPool.Get().(*slotBuffer)
+}
+
+// release method returns slot buffer into pool
+func (slot *slotBuffer) release() {
+	slotPool.Put(slot)
+}
+
+// decodedRecord holds record together with its TimeValue, so decoded record is allocated at once
+type decodedRecord struct {
+	record.Record
+	timeValue time.Time
+}
+
+// encodeRecord function encodes record into slot with checksum
+// heapPos is stored instead of StrValue longer than inline part of slot
+func encodeRecord(slot []byte, rec *record.Record, heapPos int64) error {
+	if err := encodeTime(slot[timeValueOffset:checksumOffset], rec.TimeValue); err != nil {
+		return errors.WithStack(err)
+	}
+
+	binary.LittleEndian.PutUint64(slot, uint64(rec.Id))
+	binary.LittleEndian.PutUint64(slot[deletedAtOffset:], 0)
+	binary.LittleEndian.PutUint64(slot[versionOffset:], uint64(rec.Version))
+	binary.LittleEndian.PutUint64(slot[intValueOffset:], uint64(rec.IntValue))
+	binary.LittleEndian.PutUint32(slot[strLengthOffset:], uint32(len(rec.StrValue)))
+
+	strBytes := slot[strValueOffset:boolValueOffset]
+	var n int
+
+	if len(rec.StrValue) > inlineStrLength {
+		binary.LittleEndian.PutUint64(strBytes, uint64(heapPos))
+		n = 8
+	} else {
+		n = copy(strBytes, rec.StrValue)
+	}
+
+	// slot from pool contains bytes of previous record
+	for i := n; i < len(strBytes); i++ {
+		strBytes[i] = 0
+	}
+
+	slot[boolValueOffset] = 0
+	if rec.BoolValue {
+		slot[boolValueOffset] = 1
+	}
+
+	binary.LittleEndian.PutUint32(slot[checksumOffset:], crc32.Checksum(slot[payloadOffset:checksumOffset], castagnoliTable))
+	slot[recordSize-1] = '\n'
+
+	return nil
+}
+
+// decodeRecord function decodes record from slot into new record, long StrValue is read from heap file
+// record with its TimeValue and StrValue are the only allocations
+func decodeRecord(slot []byte, heapFile *os.File) (*record.Record, error) {
+	decoded := &decodedRecord{}
+	decoded.TimeValue = &decoded.timeValue
+
+	if err := decodeRecordInto(slot, heapFile, &decoded.Record); err != nil {
+		return nil, err
+	}
+
+	return &decoded.Record, nil
+}
+
+// decodeRecordInto function decodes record from slot into record supplied by caller, so scan can reuse one record
+// TimeValue is decoded into time of record, StrValue is allocated only when it differs from StrValue of record,
+// so decoding of record with unchanged inline StrValue does not allocate
+func decodeRecordInto(slot []byte, heapFile *os.File, rec *record.Record) error {
+	strLength := binary.LittleEndian.Uint32(slot[strLengthOffset:])
+
+	if strLength > inlineStrLength {
+		value, err := readHeap(heapFile, int64(binary.LittleEndian.Uint64(slot[strValueOffset:])), strLength)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		rec.StrValue = value
+	} else if strBytes := slot[strValueOffset : strValueOffset+strLength]; rec.StrValue != string(strBytes) {
+		rec.StrValue = string(strBytes)
+	}
+
+	timeValue, err := decodeTime(slot[timeValueOffset:checksumOffset])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if rec.TimeValue == nil {
+		rec.TimeValue = new(time.Time)
+	}
+
+	rec.Id = int64(binary.LittleEndian.Uint64(slot))
+	rec.Version = int64(binary.LittleEndian.Uint64(slot[versionOffset:]))
+	rec.IntValue = int64(binary.LittleEndian.Uint64(slot[intValueOffset:]))
+	rec.BoolValue = slot[boolValueOffset] != 0
+	*rec.TimeValue = timeValue
+
+	return nil
+}
+
+// validChecksum function verifies checksum of record payload stored in slot
+func validChecksum(slot []byte) bool {
+	checksum := crc32.Checksum(slot[payloadOffset:checksumOffset], castagnoliTable)
+
+	return checksum == binary.LittleEndian.Uint32(slot[checksumOffset:])
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"encoding/binary"
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordCodecRoundTrip(t *testing.T) {
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	tests := []struct {
+		name string
+		rec  record.Record
+	}{{
+		name: "Record with all fields",
+		rec:  record.Record{Id: 7, IntValue: -42, StrValue: "foo", BoolValue: true, TimeValue: &testingTime},
+	}, {
+		name: "Record with empty StrValue",
+		rec:  record.Record{Id: 1, IntValue: 42, TimeValue: &testingTime},
+	}, {
+		name: "Record with StrValue of inline length",
+		rec:  record.Record{Id: 2, StrValue: strings.Repeat("x", inlineStrLength), TimeValue: &testingTime},
+	}, {
+		name: "Deleted record",
+		rec:  record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slot := newSlot()
+			defer slot.release()
+
+			// previous content of pooled slot must not leak into encoded record
+			copy(slot[:], strings.Repeat("\xff", recordSize))
+
+			assert.NoError(t, encodeRecord(slot[:], &tt.rec, 0))
+			assert.True(t, validChecksum(slot[:]))
+			assert.Equal(t, byte('\n'), slot[recordSize-1])
+
+			rec, err := decodeRecord(slot[:], nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.rec.Id, rec.Id)
+			assert.Equal(t, tt.rec.IntValue, rec.IntValue)
+			assert.Equal(t, tt.rec.StrValue, rec.StrValue)
+			assert.Equal(t, tt.rec.BoolValue, rec.BoolValue)
+			assert.Equal(t, *tt.rec.TimeValue, *rec.TimeValue)
+		})
+	}
+}
+
+func TestRecordCodecLongStrValue(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "codec_heap.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+	longValue := strings.Repeat("long value ", 20)
+
+	_, err = tmpfile.WriteAt(encodeHeapEntry(longValue), 10)
+	assert.NoError(t, err)
+
+	slot := newSlot()
+	defer slot.release()
+
+	assert.NoError(t, encodeRecord(slot[:], &record.Record{Id: 1, StrValue: longValue, TimeValue: &testingTime}, 10))
+	assert.Equal(t, uint64(10), binary.LittleEndian.Uint64(slot[strValueOffset:]))
+
+	rec, err := decodeRecord(slot[:], tmpfile)
+	assert.NoError(t, err)
+	assert.Equal(t, longValue, rec.StrValue)
+}
+
+func TestEncodeRecordWithoutTimeValue(t *testing.T) {
+	slot := newSlot()
+	defer slot.release()
+
+	assert.Error(t, encodeRecord(slot[:], &record.Record{Id: 1}, 0))
+}
+
+func TestRecordCodecAllocations(t *testing.T) {
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+	rec := record.Record{Id: 1, IntValue: 42, StrValue: "foo", BoolValue: true, TimeValue: &testingTime}
+
+	encodeAllocs := testing.AllocsPerRun(100, func() {
+		slot := newSlot()
+		_ = encodeRecord(slot[:], &rec, 0)
+		slot.release()
+	})
+	assert.Zero(t, encodeAllocs)
+
+	slot := newSlot()
+	defer slot.release()
+	assert.NoError(t, encodeRecord(slot[:], &rec, 0))
+
+	// new record returned to caller with its TimeValue and StrValue are the only allocations
+	decodeAllocs := testing.AllocsPerRun(100, func() {
+		_, _ = decodeRecord(slot[:], nil)
+	})
+	assert.Equal(t, float64(2), decodeAllocs)
+
+	// record supplied by caller is reused, StrValue is not allocated again
+	decoded := &record.Record{}
+	decodeIntoAllocs := testing.AllocsPerRun(100, func() {
+		_ = decodeRecordInto(slot[:], nil, decoded)
+	})
+	assert.Zero(t, decodeIntoAllocs)
+	assert.Equal(t, rec.StrValue, decoded.StrValue)
+	assert.True(t, testingTime.Equal(*decoded.TimeValue))
+}
+
+func BenchmarkEncodeRecord(b *testing.B) {
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+	rec := record.Record{Id: 1, IntValue: 42, StrValue: "foo", BoolValue: true, TimeValue: &testingTime}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		slot := newSlot()
+
+		if err := encodeRecord(slot[:], &rec, 0); err != nil {
+			b.Fatal(err)
+		}
+
+		slot.release()
+	}
+}
+
+func BenchmarkDecodeRecordInto(b *testing.B) {
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	slot := newSlot()
+	defer slot.release()
+
+	if err := encodeRecord(slot[:], &record.Record{Id: 1, IntValue: 42, StrValue: "foo", BoolValue: true, TimeValue: &testingTime}, 0); err != nil {
+		b.Fatal(err)
+	}
+
+	decoded := &record.Record{}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := decodeRecordInto(slot[:], nil, decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRecord(b *testing.B) {
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	slot := newSlot()
+	defer slot.release()
+
+	if err := encodeRecord(slot[:], &record.Record{Id: 1, IntValue: 42, StrValue: "foo", BoolValue: true, TimeValue: &testingTime}, 0); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := decodeRecord(slot[:], nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
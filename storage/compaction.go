@@ -0,0 +1,492 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	compactFileSuffix      = ".compact"
+	compactionMarkerSuffix = ".compacting"
+)
+
+// compaction structure holds files written by compaction
+type compaction struct {
+	storageFile    *os.File
+	heapFile       *os.File
+	heapSize       int64
+	heapGeneration uint32
+	idMapping      map[int64]int64
+	deletedIDs     map[int64]bool
+}
+
+// compactionMarker is written before compacted files replace files of storage
+// files are renamed when storage is opened after compaction interrupted during renames
+type compactionMarker struct {
+	Files []string `json:"files"`
+}
+
+// Compact method rewrites storage file without deleted records (online compaction)
+// Deleted records within retention window are kept, so they can be restored with their new id
+// Storage is locked during whole compaction, compacted storage, history and idempotency files
+// and indexes are written before any file of storage is replaced, files are replaced as one unit
+// Heap file is rewritten into new generation with StrValues of not deleted records only
+// Method returns mapping old id -> new id of records which were moved
+func (service *service) Compact() (map[int64]int64, error) {
+	service.lockWrite()
+	defer service.unlockWrite()
+
+	// positions in write-ahead log are not valid for compacted file
+	if err := service.checkpoint(true); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	compacted, err := compactInto(service.storageFile, service.storageFilePath, service.deletedRetention)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var (
+		compactedPaths []string
+		compactedKeys  map[string]idempotencyKey
+		indexes        map[string]*index
+		searchIndex    *searchIndex
+	)
+
+	err = func() error {
+		// history entries of moved records are rewritten with new ids
+		if service.historyFile != nil {
+			compactedHistoryPath, err := compactHistory(service.storageFilePath, compacted, service.maxStrLength)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			if compactedHistoryPath != "" {
+				compactedPaths = append(compactedPaths, compactedHistoryPath)
+			}
+		}
+
+		// idempotency keys of moved records are changed
+		if service.idempotencyFile != nil {
+			compactedKeys = compactIdempotencyKeys(service.idempotencyKeys, compacted)
+			compactedIdempotencyPath := idempotencyFilePath(service.storageFilePath) + compactFileSuffix
+
+			if err := writeIdempotencyFile(compactedIdempotencyPath, compactedKeys); err != nil {
+				return errors.WithStack(err)
+			}
+
+			compactedPaths = append(compactedPaths, compactedIdempotencyPath)
+		}
+
+		// ids of moved records are changed
+		indexes = make(map[string]*index, len(service.indexes))
+		rebuild := make([]*index, 0, len(service.indexes))
+
+		for field, idx := range service.indexes {
+			indexes[field] = &index{field: idx.field, keyOf: idx.keyOf}
+			rebuild = append(rebuild, indexes[field])
+		}
+
+		if err := rebuildIndexes(compacted.storageFile, rebuild); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if service.searchIndex != nil {
+			var err error
+
+			if searchIndex, err = buildSearchIndex(compacted.storageFile, compacted.heapFile); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		compactedPaths = append(compactedPaths, compacted.storageFile.Name())
+
+		return writeCompactionMarker(service.storageFilePath, compactedPaths)
+	}()
+
+	if err != nil {
+		compacted.remove()
+		removeFiles(compactedPaths)
+		return nil, errors.WithStack(err)
+	}
+
+	if err := finishCompaction(service.storageFilePath, compactedPaths); err != nil {
+		// files of storage are partially replaced, renames are finished when storage is opened again
+		log.Errorf("Compaction of storage file interrupted, storage is closed: %s", err)
+		compacted.storageFile.Close()
+		compacted.heapFile.Close()
+		service.walFile.Close()
+		service.closeFiles()
+		return nil, errors.WithStack(err)
+	}
+
+	service.storageFile.Close()
+	service.heapFile.Close()
+	os.Remove(service.heapFile.Name())
+
+	service.storageFile = compacted.storageFile
+	service.heapFile = compacted.heapFile
+	service.heapSize = compacted.heapSize
+	service.heapGeneration = compacted.heapGeneration
+	service.indexes = indexes
+	service.searchIndex = searchIndex
+
+	if service.historyFile != nil {
+		service.historyFile.Close()
+
+		if err := service.openHistory(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if service.idempotencyFile != nil {
+		service.idempotencyKeys = compactedKeys
+
+		if err := service.reopenIdempotencyFile(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	// slots of kept deleted records are reused when their retention elapses
+	service.freeSlots = nil
+
+	if service.reuseSlots {
+		if err := service.loadFreeSlots(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	log.Infof("Storage file compacted, %d records moved", len(compacted.idMapping))
+	return compacted.idMapping, nil
+}
+
+// CompactFile function rewrites storage file without deleted records (offline compaction)
+// Function must not be used when storage file is opened by running service
+// Deleted records within retention window are kept, so they can be restored with their new id
+// History entry longer than entry with StrValue of maxStrLength bytes is damaged, following entries are not kept
+// Function returns mapping old id -> new id of records which were moved
+func CompactFile(fileStoragePath string, deletedRetention time.Duration, maxStrLength int) (map[int64]int64, error) {
+	if err := recoverCompaction(fileStoragePath); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	file, err := os.Open(fileStoragePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	header, err := readFileHeader(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	compacted, err := compactInto(file, fileStoragePath, deletedRetention)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer compacted.storageFile.Close()
+	defer compacted.heapFile.Close()
+
+	var compactedPaths []string
+
+	err = func() error {
+		compactedHistoryPath, err := compactHistory(fileStoragePath, compacted, maxStrLength)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if compactedHistoryPath != "" {
+			compactedPaths = append(compactedPaths, compactedHistoryPath)
+		}
+
+		compactedIdempotencyPath, err := compactIdempotencyFile(fileStoragePath, compacted)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if compactedIdempotencyPath != "" {
+			compactedPaths = append(compactedPaths, compactedIdempotencyPath)
+		}
+
+		// indexes of compacted storage file are rebuilt when storage is opened
+		if err := removeIndexFiles(fileStoragePath); err != nil {
+			return errors.WithStack(err)
+		}
+
+		compactedPaths = append(compactedPaths, compacted.storageFile.Name())
+
+		return writeCompactionMarker(fileStoragePath, compactedPaths)
+	}()
+
+	if err != nil {
+		compacted.remove()
+		removeFiles(compactedPaths)
+		return nil, errors.WithStack(err)
+	}
+
+	if err := finishCompaction(fileStoragePath, compactedPaths); err != nil {
+		return nil, errors.Wrap(err, "compaction is finished when storage is opened")
+	}
+
+	if header != nil {
+		os.Remove(heapFilePath(fileStoragePath, header.HeapGeneration))
+	}
+
+	return compacted.idMapping, nil
+}
+
+// compactionMarkerPath function returns path of marker of compaction of storage file
+func compactionMarkerPath(fileStoragePath string) string {
+	return fileStoragePath + compactionMarkerSuffix
+}
+
+// compactedFilePaths function returns paths of files written by compaction of storage file before they are renamed
+func compactedFilePaths(fileStoragePath string) []string {
+	return []string{
+		fileStoragePath + compactFileSuffix,
+		historyFilePath(fileStoragePath) + compactFileSuffix,
+		idempotencyFilePath(fileStoragePath) + compactFileSuffix,
+	}
+}
+
+// writeCompactionMarker function writes synced marker with compacted files which replace files of storage
+func writeCompactionMarker(fileStoragePath string, compactedPaths []string) error {
+	marker := compactionMarker{Files: make([]string, 0, len(compactedPaths))}
+
+	for _, path := range compactedPaths {
+		marker.Files = append(marker.Files, filepath.Base(path))
+	}
+
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	markerFile, err := os.OpenFile(compactionMarkerPath(fileStoragePath), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer markerFile.Close()
+
+	if _, err := markerFile.Write(data); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := markerFile.Sync(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return syncDir(filepath.Dir(fileStoragePath))
+}
+
+// finishCompaction function renames compacted files over files of storage and removes marker of compaction
+// compacted file which does not exist was renamed before compaction was interrupted
+func finishCompaction(fileStoragePath string, compactedPaths []string) error {
+	for _, path := range compactedPaths {
+		if err := os.Rename(path, strings.TrimSuffix(path, compactFileSuffix)); err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := syncDir(filepath.Dir(fileStoragePath)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Remove(compactionMarkerPath(fileStoragePath)))
+}
+
+// recoverCompaction function finishes compaction interrupted during renames of compacted files
+// compacted files left by compaction interrupted before its marker was written are removed
+func recoverCompaction(fileStoragePath string) error {
+	data, err := os.ReadFile(compactionMarkerPath(fileStoragePath))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+
+	var marker compactionMarker
+
+	// incomplete marker is left by compaction interrupted before any file was renamed
+	if err != nil || json.Unmarshal(data, &marker) != nil {
+		removeFiles(append(compactedFilePaths(fileStoragePath), compactionMarkerPath(fileStoragePath)))
+
+		return nil
+	}
+
+	compactedPaths := make([]string, 0, len(marker.Files))
+
+	for _, name := range marker.Files {
+		if filepath.Base(name) != name || !strings.HasSuffix(name, compactFileSuffix) {
+			return errors.Errorf("marker of compaction contains invalid file name %q", name)
+		}
+
+		compactedPaths = append(compactedPaths, filepath.Join(filepath.Dir(fileStoragePath), name))
+	}
+
+	if err := finishCompaction(fileStoragePath, compactedPaths); err != nil {
+		return errors.WithStack(err)
+	}
+
+	log.Warnf("Interrupted compaction of storage file %s finished", fileStoragePath)
+	return nil
+}
+
+// removeFiles function removes files, errors are ignored
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// compactInto function copies header and all not deleted records from source file into new file
+// deleted records which can be restored are copied until their retention elapses, empty slots are dropped
+// ids of records are renumbered according to the new position of record
+// long StrValues of copied records are copied into heap file of next generation
+func compactInto(source *os.File, fileStoragePath string, deletedRetention time.Duration) (*compaction, error) {
+	header, err := readFileHeader(source)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if header == nil {
+		header = newFileHeader()
+	} else if err := header.validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sourceHeap, err := os.OpenFile(heapFilePath(fileStoragePath, header.HeapGeneration), os.O_CREATE|os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer sourceHeap.Close()
+
+	compacted := &compaction{
+		heapGeneration: header.HeapGeneration + 1,
+		idMapping:      make(map[int64]int64),
+		deletedIDs:     make(map[int64]bool),
+	}
+
+	compacted.storageFile, err = os.OpenFile(fileStoragePath+compactFileSuffix, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	compacted.heapFile, err = os.OpenFile(heapFilePath(fileStoragePath, compacted.heapGeneration), os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		compacted.storageFile.Close()
+		os.Remove(compacted.storageFile.Name())
+		return nil, errors.WithStack(err)
+	}
+
+	header.HeapGeneration = compacted.heapGeneration
+
+	if _, err := compacted.storageFile.Write(header.encode()); err != nil {
+		compacted.remove()
+		return nil, errors.WithStack(err)
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(source, headerSize)
+	slot := buffer[:]
+	newID := int64(1)
+
+	for oldID := int64(1); ; oldID++ {
+		// incomplete record at the end of file is skipped
+		if _, err := io.ReadFull(reader, slot); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			compacted.remove()
+			return nil, errors.WithStack(err)
+		}
+
+		deleted := int64(binary.LittleEndian.Uint64(slot)) == 0
+
+		if deleted && (!restorable(slot) || time.Since(deletedTime(slot)) >= deletedRetention) {
+			compacted.deletedIDs[oldID] = true
+			continue
+		}
+
+		// id stays zero in slot of deleted record
+		if oldID != newID {
+			if !deleted {
+				binary.LittleEndian.PutUint64(slot, uint64(newID))
+			}
+
+			compacted.idMapping[oldID] = newID
+		}
+
+		if err := compacted.copyHeapEntry(slot, sourceHeap); err != nil {
+			compacted.remove()
+			return nil, errors.WithStack(err)
+		}
+
+		if _, err := compacted.storageFile.Write(slot); err != nil {
+			compacted.remove()
+			return nil, errors.WithStack(err)
+		}
+
+		newID++
+	}
+
+	if err := compacted.heapFile.Sync(); err != nil {
+		compacted.remove()
+		return nil, errors.WithStack(err)
+	}
+
+	if err := compacted.storageFile.Sync(); err != nil {
+		compacted.remove()
+		return nil, errors.WithStack(err)
+	}
+
+	return compacted, nil
+}
+
+// copyHeapEntry method copies heap entry of long StrValue into heap file of compaction
+// position of heap entry in slot is updated, corrupted slot is copied without change
+func (compacted *compaction) copyHeapEntry(slot []byte, sourceHeap *os.File) error {
+	strLength := binary.LittleEndian.Uint32(slot[strLengthOffset:])
+
+	if strLength <= inlineStrLength || !validChecksum(slot) {
+		return nil
+	}
+
+	entry := make([]byte, int(strLength)+heapEntryOverhead)
+
+	// damaged heap entry is detected by checksum when record is read
+	if _, err := sourceHeap.ReadAt(entry, int64(binary.LittleEndian.Uint64(slot[strValueOffset:]))); err == io.EOF {
+		log.Warnf("Heap entry of record %d is incomplete", int64(binary.LittleEndian.Uint64(slot)))
+		return nil
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if _, err := compacted.heapFile.WriteAt(entry, compacted.heapSize); err != nil {
+		return errors.WithStack(err)
+	}
+
+	binary.LittleEndian.PutUint64(slot[strValueOffset:], uint64(compacted.heapSize))
+	binary.LittleEndian.PutUint32(slot[checksumOffset:], crc32.Checksum(slot[payloadOffset:checksumOffset], castagnoliTable))
+
+	compacted.heapSize += int64(len(entry))
+
+	return nil
+}
+
+// remove method closes and removes files written by compaction
+func (compacted *compaction) remove() {
+	compacted.storageFile.Close()
+	compacted.heapFile.Close()
+	os.Remove(compacted.storageFile.Name())
+	os.Remove(compacted.heapFile.Name())
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompact(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "compact_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service, err := NewService(tmpfile.Name(), WithSlotReuse(true))
+	assert.NoError(t, err)
+	defer service.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 5; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	for _, id := range []int64{1, 3} {
+		deleted, err := service.DeleteRecord(id)
+		assert.NoError(t, err)
+		assert.True(t, deleted)
+	}
+
+	idMapping, err := service.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int64{2: 1, 4: 2, 5: 3}, idMapping)
+
+	stat, err := os.Stat(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+3*recordSize), stat.Size())
+
+	for oldID, newID := range idMapping {
+		rec, err := service.GetRecord(newID)
+		assert.NoError(t, err)
+		assert.NotNil(t, rec)
+		assert.Equal(t, newID, rec.Id)
+		assert.Equal(t, oldID, rec.IntValue)
+	}
+
+	// free slots are dropped by compaction, new record is appended
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 6, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), createdID)
+}
+
+func TestCompactFile(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "compact_file_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service, err := NewService(tmpfile.Name())
+	assert.NoError(t, err)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 3; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err = service.DeleteRecord(2)
+	assert.NoError(t, err)
+
+	service.Close()
+
+	idMapping, err := CompactFile(tmpfile.Name(), 0, defaultMaxStrLength)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int64{3: 2}, idMapping)
+
+	service, err = NewService(tmpfile.Name())
+	assert.NoError(t, err)
+	defer service.Close()
+
+	rec, err := service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.NotNil(t, rec)
+	assert.Equal(t, int64(3), rec.IntValue)
+}
+
+func TestRecoverInterruptedCompaction(t *testing.T) {
+	storageFilePath := filepath.Join(t.TempDir(), "records.bin")
+
+	service := newTestService(t, storageFilePath, WithHistory(true), WithIdempotencyTTL(time.Hour))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 2; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err := service.CreateRecordIdempotent("key", &record.Record{IntValue: 3, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.EditRecord(3, &record.Record{IntValue: 4, StrValue: "bar", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.DeleteRecord(2)
+	assert.NoError(t, err)
+
+	service.Close()
+
+	// compaction is interrupted after marker is written and history file is renamed
+	file, err := os.Open(storageFilePath)
+	assert.NoError(t, err)
+
+	compacted, err := compactInto(file, storageFilePath, 0)
+	assert.NoError(t, err)
+	file.Close()
+	compacted.storageFile.Close()
+	compacted.heapFile.Close()
+
+	compactedHistoryPath, err := compactHistory(storageFilePath, compacted, defaultMaxStrLength)
+	assert.NoError(t, err)
+
+	compactedIdempotencyPath, err := compactIdempotencyFile(storageFilePath, compacted)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writeCompactionMarker(storageFilePath, []string{compactedHistoryPath, compactedIdempotencyPath, compacted.storageFile.Name()}))
+	assert.NoError(t, os.Rename(compactedHistoryPath, historyFilePath(storageFilePath)))
+
+	service = newTestService(t, storageFilePath, WithHistory(true), WithIdempotencyTTL(time.Hour))
+
+	for _, path := range append(compactedFilePaths(storageFilePath), compactionMarkerPath(storageFilePath)) {
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	rec, err := service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), rec.IntValue)
+
+	history, err := service.RecordHistory(2)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	rec = &record.Record{IntValue: 3, StrValue: "foo", TimeValue: &testingTime}
+	created, err := service.CreateRecordIdempotent("key", rec)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, int64(2), rec.Id)
+}
+
+func TestRemoveCompactionWithoutMarker(t *testing.T) {
+	storageFilePath := filepath.Join(t.TempDir(), "records.bin")
+
+	service := newTestService(t, storageFilePath)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 2; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err := service.DeleteRecord(1)
+	assert.NoError(t, err)
+
+	service.Close()
+
+	// compaction is interrupted before marker is written, storage file is not replaced
+	file, err := os.Open(storageFilePath)
+	assert.NoError(t, err)
+
+	compacted, err := compactInto(file, storageFilePath, 0)
+	assert.NoError(t, err)
+	file.Close()
+	compacted.storageFile.Close()
+	compacted.heapFile.Close()
+
+	service = newTestService(t, storageFilePath)
+
+	_, err = os.Stat(compacted.storageFile.Name())
+	assert.True(t, os.IsNotExist(err))
+
+	rec, err := service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rec.IntValue)
+}
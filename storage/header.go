@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Header of storage file is stored at the beginning of file, records follow the header
+// magic(8) | format version(4) | record size(4) | creation time(8) | heap generation(4) | reserved(32) | crc32c(4)
+const (
+	headerSize    = 64
+	formatVersion = 5
+	// buffer of slot reader holds 64 record slots
+	slotReaderSize = 64 * recordSize
+)
+
+var headerMagic = []byte("IVTREC\x00\x01")
+
+var (
+	// ErrInvalidHeader error of storage file with damaged or unknown header
+	ErrInvalidHeader = errors.New("invalid storage file header")
+	// ErrUnsupportedVersion error of storage file created by newer version of application
+	ErrUnsupportedVersion = errors.New("unsupported storage file version")
+	// ErrMigrationRequired error of storage file in older format which must be migrated
+	ErrMigrationRequired = errors.New("storage file must be migrated")
+)
+
+type fileHeader struct {
+	Version        uint32
+	RecordSize     uint32
+	CreatedAt      time.Time
+	HeapGeneration uint32
+}
+
+// newFileHeader function returns header for storage file in current format
+func newFileHeader() *fileHeader {
+	return &fileHeader{
+		Version:    formatVersion,
+		RecordSize: recordSize,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// encode method encodes header into bytes
+func (header *fileHeader) encode() []byte {
+	buffer := make([]byte, headerSize)
+	copy(buffer, headerMagic)
+	binary.LittleEndian.PutUint32(buffer[8:], header.Version)
+	binary.LittleEndian.PutUint32(buffer[12:], header.RecordSize)
+	binary.LittleEndian.PutUint64(buffer[16:], uint64(header.CreatedAt.UnixNano()))
+	binary.LittleEndian.PutUint32(buffer[24:], header.HeapGeneration)
+	binary.LittleEndian.PutUint32(buffer[headerSize-4:], crc32.Checksum(buffer[:headerSize-4], castagnoliTable))
+
+	return buffer
+}
+
+// readFileHeader function reads header of storage file
+// headerless file from first version of application is returned as version 0
+// nil header is returned for empty file or file with incomplete header
+func readFileHeader(file *os.File) (*fileHeader, error) {
+	buffer := make([]byte, headerSize)
+
+	n, err := file.ReadAt(buffer, 0)
+	if err != nil && err != io.EOF {
+		return nil, errors.WithStack(err)
+	}
+
+	if n < headerSize {
+		prefix := buffer[:n]
+
+		if n > len(headerMagic) {
+			prefix = buffer[:len(headerMagic)]
+		}
+
+		// header of new file was not completely written, file does not contain any record
+		if bytes.HasPrefix(headerMagic, prefix) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(ErrInvalidHeader, "file is too short")
+	}
+
+	// any version 0 file with record is longer than header
+	if !bytes.Equal(buffer[:len(headerMagic)], headerMagic) {
+		return &fileHeader{Version: 0, RecordSize: v0RecordSize}, nil
+	}
+
+	if crc32.Checksum(buffer[:headerSize-4], castagnoliTable) != binary.LittleEndian.Uint32(buffer[headerSize-4:]) {
+		return nil, errors.Wrap(ErrInvalidHeader, "header checksum mismatch")
+	}
+
+	return &fileHeader{
+		Version:        binary.LittleEndian.Uint32(buffer[8:]),
+		RecordSize:     binary.LittleEndian.Uint32(buffer[12:]),
+		CreatedAt:      time.Unix(0, int64(binary.LittleEndian.Uint64(buffer[16:]))),
+		HeapGeneration: binary.LittleEndian.Uint32(buffer[24:]),
+	}, nil
+}
+
+// validate method checks that header describes file in current format
+func (header *fileHeader) validate() error {
+	if header.Version == 0 {
+		return errors.Wrapf(ErrMigrationRequired, "version %d", header.Version)
+	}
+
+	// formats between version 0 and current version were never released
+	if header.Version != formatVersion {
+		return errors.Wrapf(ErrUnsupportedVersion, "version %d", header.Version)
+	}
+
+	if header.RecordSize != recordSize {
+		return errors.Wrapf(ErrInvalidHeader, "record size %d", header.RecordSize)
+	}
+
+	return nil
+}
+
+// openHeader method writes header into new storage file or validates header of existing file
+// file in older format is migrated in place when auto migration is enabled
+// heap file of generation from header is opened
+func (service *service) openHeader() error {
+	header, err := readFileHeader(service.storageFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if header == nil {
+		header = newFileHeader()
+
+		if _, err := service.storageFile.WriteAt(header.encode(), 0); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := service.storageFile.Sync(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return service.openHeap(header.HeapGeneration)
+	}
+
+	if header.Version == 0 && service.autoMigrate {
+		if err := MigrateFile(service.storageFilePath, service.storageFilePath); err != nil {
+			return errors.WithStack(err)
+		}
+
+		file, err := os.OpenFile(service.storageFilePath, os.O_RDWR, os.ModePerm)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		service.storageFile.Close()
+		service.storageFile = file
+
+		return service.openHeader()
+	}
+
+	if err := header.validate(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return service.openHeap(header.HeapGeneration)
+}
+
+// truncateTornRecord method discards incomplete record at the end of storage file
+func (service *service) truncateTornRecord() error {
+	stat, err := service.storageFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if tornSize := (stat.Size() - headerSize) % recordSize; tornSize != 0 {
+		log.Warnf("Incomplete record at the end of storage file discarded (%d bytes)", tornSize)
+
+		if err := service.storageFile.Truncate(stat.Size() - tornSize); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// slotPosition function returns position of record slot in storage file
+func slotPosition(id int64) int64 {
+	return headerSize + (id-1)*recordSize
+}
+
+// slotID function returns id of record stored in slot at position
+func slotID(pos int64) int64 {
+	return (pos-headerSize)/recordSize + 1
+}
+
+// slotReader function returns buffered reader of record slots from position to the end of storage file
+// reader uses positional reads, so offset of file is not shared and readers do not block each other
+func slotReader(file *os.File, pos int64) *bufio.Reader {
+	return bufio.NewReaderSize(io.NewSectionReader(file, pos, math.MaxInt64-pos), slotReaderSize)
+}
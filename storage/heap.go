@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"interviewtest/record"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// StrValue longer than inline part of record slot is stored in heap file next to storage file
+// record slot holds position of heap entry, heap entry is length(4) | StrValue | crc32c(4)
+// heap file is replaced by compaction, generation of heap file is stored in header of storage file
+const (
+	heapEntryOverhead   = 8
+	defaultMaxStrLength = 4096
+)
+
+// heapFilePath function returns path of heap file of given generation
+func heapFilePath(fileStoragePath string, generation uint32) string {
+	return fmt.Sprintf("%s.heap.%d", fileStoragePath, generation)
+}
+
+// openHeap method opens heap file of current generation and removes heap files of other generations
+func (service *service) openHeap(generation uint32) error {
+	heapFile, err := os.OpenFile(heapFilePath(service.storageFilePath, generation), os.O_CREATE|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.heapFile = heapFile
+	service.heapGeneration = generation
+
+	stat, err := service.storageFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// heap of file without records is not referenced
+	if stat.Size() <= headerSize {
+		if err := heapFile.Truncate(0); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	heapStat, err := heapFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.heapSize = heapStat.Size()
+
+	// heap files of other generations are left by interrupted compaction
+	heapFiles, err := filepath.Glob(service.storageFilePath + ".heap.*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// paths returned by glob are cleaned, storage path can be relative like ./records.bin
+	for _, path := range heapFiles {
+		if path != filepath.Clean(heapFile.Name()) {
+			log.Infof("Unused heap file %s removed", path)
+			os.Remove(path)
+		}
+	}
+
+	return nil
+}
+
+// appendHeap method appends StrValue to heap file and returns position of heap entry
+func (service *service) appendHeap(value string) (int64, error) {
+	pos := service.heapSize
+
+	if _, err := service.heapFile.WriteAt(encodeHeapEntry(value), pos); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	// heap entry must be persisted before record which refers to it
+	if service.syncPolicy == SyncAlways {
+		if err := service.heapFile.Sync(); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+
+	service.heapSize += int64(len(value) + heapEntryOverhead)
+
+	return pos, nil
+}
+
+// readHeap function reads StrValue of given length from heap file and verifies its checksum
+func readHeap(heapFile *os.File, pos int64, length uint32) (string, error) {
+	entry := make([]byte, int(length)+heapEntryOverhead)
+
+	if _, err := heapFile.ReadAt(entry, pos); err != nil {
+		return "", errors.Wrapf(record.ErrCorruptRecord, "heap entry at %d: %s", pos, err)
+	}
+
+	if binary.LittleEndian.Uint32(entry) != length {
+		return "", errors.Wrapf(record.ErrCorruptRecord, "heap entry at %d has different length", pos)
+	}
+
+	value := entry[4 : 4+length]
+
+	if crc32.Checksum(value, castagnoliTable) != binary.LittleEndian.Uint32(entry[4+length:]) {
+		return "", errors.Wrapf(record.ErrCorruptRecord, "heap entry at %d checksum mismatch", pos)
+	}
+
+	return string(value), nil
+}
+
+// encodeHeapEntry function encodes StrValue into heap entry
+func encodeHeapEntry(value string) []byte {
+	entry := make([]byte, len(value)+heapEntryOverhead)
+	binary.LittleEndian.PutUint32(entry, uint32(len(value)))
+	copy(entry[4:], value)
+	binary.LittleEndian.PutUint32(entry[4+len(value):], crc32.Checksum(entry[4:4+len(value)], castagnoliTable))
+
+	return entry
+}
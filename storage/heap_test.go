@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongStrValue(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "heap_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithMaxStrLength(256))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	tests := []struct {
+		name     string
+		strValue string
+	}{{
+		name:     "Inline StrValue with trailing zero bytes",
+		strValue: "foo\x00\x00",
+	}, {
+		name:     "Inline StrValue of maximal inline length",
+		strValue: strings.Repeat("a", inlineStrLength),
+	}, {
+		name:     "StrValue stored in heap",
+		strValue: strings.Repeat("foo bar ", 20),
+	}, {
+		name:     "Multi-byte StrValue stored in heap",
+		strValue: strings.Repeat("žluťoučký kůň ", 10),
+	}, {
+		name:     "StrValue of maximal length",
+		strValue: strings.Repeat("b", 256),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdID, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: tt.strValue, TimeValue: &testingTime})
+			assert.NoError(t, err)
+
+			rec, err := service.GetRecord(createdID)
+			assert.NoError(t, err)
+			assert.NotNil(t, rec)
+			assert.Equal(t, tt.strValue, rec.StrValue)
+
+			_, err = service.EditRecord(createdID, &record.Record{Id: createdID, IntValue: 42, StrValue: tt.strValue + "!", TimeValue: &testingTime})
+
+			if len(tt.strValue) == 256 {
+				assert.ErrorIs(t, err, record.ErrStrValueTooLong)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			rec, err = service.GetRecord(createdID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.strValue+"!", rec.StrValue)
+		})
+	}
+}
+
+func TestStrValueTooLong(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "too_long_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithMaxStrLength(100))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: strings.Repeat("a", 101), TimeValue: &testingTime})
+	assert.ErrorIs(t, err, record.ErrStrValueTooLong)
+	assert.Zero(t, createdID)
+
+	stats, err := service.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Stats{}, *stats)
+}
+
+func TestCorruptHeapEntry(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "corrupt_heap_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: strings.Repeat("a", 100), TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.heapFile.WriteAt([]byte{'b'}, 10)
+	assert.NoError(t, err)
+
+	rec, err := service.GetRecord(createdID)
+	assert.Nil(t, rec)
+	assert.ErrorIs(t, err, record.ErrCorruptRecord)
+
+	stats, err := service.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Stats{CorruptRecords: 1}, *stats)
+}
+
+func TestCompactHeap(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "compact_heap_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	values := []string{strings.Repeat("a", 100), strings.Repeat("b", 200), "short", strings.Repeat("c", 300)}
+
+	for _, value := range values {
+		_, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: value, TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err = service.DeleteRecord(2)
+	assert.NoError(t, err)
+
+	idMapping, err := service.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int64{3: 2, 4: 3}, idMapping)
+
+	_, err = os.Stat(heapFilePath(tmpfile.Name(), 0))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Equal(t, int64(100+300+2*heapEntryOverhead), service.heapSize)
+
+	for id, value := range map[int64]string{1: values[0], 2: values[2], 3: values[3]} {
+		rec, err := service.GetRecord(id)
+		assert.NoError(t, err)
+		assert.NotNil(t, rec)
+		assert.Equal(t, value, rec.StrValue)
+	}
+
+	// heap of new generation is used after restart
+	service.Close()
+	service = newTestService(t, tmpfile.Name())
+
+	rec, err := service.GetRecord(3)
+	assert.NoError(t, err)
+	assert.Equal(t, values[3], rec.StrValue)
+}
+
+func TestOpenHeapRemovesUnusedHeapFiles(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "unused_heap_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	// heap file left by interrupted compaction
+	err = os.WriteFile(heapFilePath(tmpfile.Name(), 1), []byte("unused"), os.ModePerm)
+	assert.NoError(t, err)
+
+	newTestService(t, tmpfile.Name())
+
+	_, err = os.Stat(heapFilePath(tmpfile.Name(), 1))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,417 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"interviewtest/record"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Previous version of record is appended to history file next to storage file before record is changed
+// history entry is length(4) | id(8) | changedAt(8) | version(8) | exists(1) | IntValue(8) | BoolValue(1) |
+// TimeValue(16) | StrValue | crc32c(4), length is length of whole entry and changedAt is unix nanoseconds
+// entry with exists 0 is written when record is created, record did not exist before the change
+const (
+	historyFileSuffix    = ".history"
+	historyEntryOverhead = 58
+	historyIDOffset      = 4
+	historyChangedOffset = 12
+	historyVersionOffset = 20
+	historyExistsOffset  = 28
+	historyIntOffset     = 29
+	historyBoolOffset    = 37
+	historyTimeOffset    = 38
+	historyStrOffset     = 54
+)
+
+// historyRef reference of history entry with time of change
+type historyRef struct {
+	pos       int64
+	changedAt int64
+}
+
+// historyFilePath function returns path of history file of storage file
+func historyFilePath(fileStoragePath string) string {
+	return fileStoragePath + historyFileSuffix
+}
+
+// openHistory method opens history file and loads references of history entries
+// incomplete entry at the end of file is left by interrupted write, it is truncated
+func (service *service) openHistory() error {
+	historyFile, err := os.OpenFile(historyFilePath(service.storageFilePath), os.O_CREATE|os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.historyFile = historyFile
+
+	return service.loadHistory()
+}
+
+// loadHistory method reads all history entries and builds references of entries by record id
+func (service *service) loadHistory() error {
+	service.historyEntries = make(map[int64][]historyRef)
+
+	size, err := readHistoryEntries(service.historyFile, service.maxStrLength, func(pos int64, entry []byte) error {
+		if !validHistoryChecksum(entry) {
+			log.Warnf("History entry at %d is corrupted", pos)
+			return nil
+		}
+
+		id := int64(binary.LittleEndian.Uint64(entry[historyIDOffset:]))
+		changedAt := int64(binary.LittleEndian.Uint64(entry[historyChangedOffset:]))
+		service.historyEntries[id] = append(service.historyEntries[id], historyRef{pos: pos, changedAt: changedAt})
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	stat, err := service.historyFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if stat.Size() > size {
+		log.Warnf("Incomplete history entry at %d truncated", size)
+
+		if err := service.historyFile.Truncate(size); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	service.historySize = size
+
+	return nil
+}
+
+// readHistoryEntries function reads history entries in order of file and returns size of complete entries
+// entry longer than entry with StrValue of maxStrLength bytes has damaged length and ends readable entries
+func readHistoryEntries(historyFile *os.File, maxStrLength int, fn func(pos int64, entry []byte) error) (int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(historyFile, 0, 1<<62))
+	length := make([]byte, 4)
+	pos := int64(0)
+
+	for {
+		if _, err := io.ReadFull(reader, length); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return pos, nil
+		} else if err != nil {
+			return 0, errors.WithStack(err)
+		}
+
+		entryLength := binary.LittleEndian.Uint32(length)
+
+		// damaged length can not be skipped, rest of file is not readable
+		if !validHistoryLength(entryLength, maxStrLength) {
+			return pos, nil
+		}
+
+		entry := make([]byte, entryLength)
+		copy(entry, length)
+
+		if _, err := io.ReadFull(reader, entry[4:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return pos, nil
+		} else if err != nil {
+			return 0, errors.WithStack(err)
+		}
+
+		if err := fn(pos, entry); err != nil {
+			return 0, err
+		}
+
+		pos += int64(entryLength)
+	}
+}
+
+// appendHistory method appends previous content of slot at position to history file
+// nothing is appended when slot is empty before and after the change, corrupted record is not appended
+func (service *service) appendHistory(pos int64, changed *record.Record) error {
+	buffer := newSlot()
+	defer buffer.release()
+
+	slot := buffer[:]
+
+	if _, err := service.readAt(slot, pos); err == io.EOF {
+		slot = nil
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if slot != nil && binary.LittleEndian.Uint64(slot) != 0 {
+		return service.appendHistorySlot(slotID(pos), slot)
+	}
+
+	// empty slots written before record created after end of file are not part of history
+	if changed == nil || changed.Id == 0 {
+		return nil
+	}
+
+	return service.appendHistoryEntry(slotID(pos), 0, nil)
+}
+
+// appendHistorySlot method appends record stored in slot to history file
+func (service *service) appendHistorySlot(id int64, slot []byte) error {
+	if !validChecksum(slot) {
+		log.Warnf("Corrupted record %d is not stored in history", id)
+		return nil
+	}
+
+	previous, err := decodeRecord(slot, service.heapFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return service.appendHistoryEntry(id, previous.Version, previous)
+}
+
+// appendHistoryEntry method appends history entry with previous record, nil record did not exist
+// entry of open transaction is appended by commit
+func (service *service) appendHistoryEntry(id, version int64, previous *record.Record) error {
+	if service.tx != nil {
+		service.tx.history = append(service.tx.history, historyChange{id: id, version: version, previous: previous})
+		return nil
+	}
+
+	changedAt := time.Now().UnixNano()
+
+	entry, err := encodeHistoryEntry(id, changedAt, version, previous)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	pos := service.historySize
+
+	if _, err := service.historyFile.WriteAt(entry, pos); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// history entry must be persisted before record is changed
+	if service.syncPolicy == SyncAlways {
+		if err := service.historyFile.Sync(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	service.historySize += int64(len(entry))
+	service.historyEntries[id] = append(service.historyEntries[id], historyRef{pos: pos, changedAt: changedAt})
+
+	return nil
+}
+
+// RecordHistory method returns previous versions of record in order of changes
+// history of deleted record is returned too, ErrHistoryDisabled is returned when history is not enabled
+func (service *service) RecordHistory(id int64) ([]record.Revision, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	if service.historyFile == nil {
+		return nil, errors.WithStack(record.ErrHistoryDisabled)
+	}
+
+	refs := service.historyEntries[id]
+	revisions := make([]record.Revision, 0, len(refs))
+
+	for _, ref := range refs {
+		revision, err := service.readHistoryEntry(ref.pos)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, nil
+}
+
+// GetRecordAsOf method returns record as it was at given time
+// record is taken from first history entry changed after the time, current record is returned when record was not changed since
+// nil is returned when record did not exist at the time
+func (service *service) GetRecordAsOf(id int64, asOf time.Time) (*record.Record, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	if service.historyFile == nil {
+		return nil, errors.WithStack(record.ErrHistoryDisabled)
+	}
+
+	for _, ref := range service.historyEntries[id] {
+		if ref.changedAt > asOf.UnixNano() {
+			revision, err := service.readHistoryEntry(ref.pos)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			return revision.Record, nil
+		}
+	}
+
+	return service.getRecord(id)
+}
+
+// readHistoryEntry method reads history entry at position and verifies its checksum
+func (service *service) readHistoryEntry(pos int64) (*record.Revision, error) {
+	length := make([]byte, 4)
+
+	if _, err := service.historyFile.ReadAt(length, pos); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	entryLength := binary.LittleEndian.Uint32(length)
+
+	if !validHistoryLength(entryLength, service.maxStrLength) {
+		return nil, errors.Wrapf(record.ErrCorruptRecord, "history entry length %d is out of range", entryLength)
+	}
+
+	entry := make([]byte, entryLength)
+
+	if _, err := service.historyFile.ReadAt(entry, pos); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return decodeHistoryEntry(entry)
+}
+
+// encodeHistoryEntry function encodes previous record into history entry
+func encodeHistoryEntry(id, changedAt, version int64, previous *record.Record) ([]byte, error) {
+	var strValue string
+
+	if previous != nil {
+		strValue = previous.StrValue
+	}
+
+	entry := make([]byte, historyEntryOverhead+len(strValue))
+
+	binary.LittleEndian.PutUint32(entry, uint32(len(entry)))
+	binary.LittleEndian.PutUint64(entry[historyIDOffset:], uint64(id))
+	binary.LittleEndian.PutUint64(entry[historyChangedOffset:], uint64(changedAt))
+
+	if previous != nil {
+		binary.LittleEndian.PutUint64(entry[historyVersionOffset:], uint64(version))
+		entry[historyExistsOffset] = 1
+		binary.LittleEndian.PutUint64(entry[historyIntOffset:], uint64(previous.IntValue))
+
+		if previous.BoolValue {
+			entry[historyBoolOffset] = 1
+		}
+
+		if err := encodeTime(entry[historyTimeOffset:historyStrOffset], previous.TimeValue); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		copy(entry[historyStrOffset:], strValue)
+	}
+
+	binary.LittleEndian.PutUint32(entry[len(entry)-4:], crc32.Checksum(entry[:len(entry)-4], castagnoliTable))
+
+	return entry, nil
+}
+
+// decodeHistoryEntry function decodes revision of record from history entry
+func decodeHistoryEntry(entry []byte) (*record.Revision, error) {
+	if !validHistoryChecksum(entry) {
+		return nil, errors.Wrap(record.ErrCorruptRecord, "history entry checksum mismatch")
+	}
+
+	revision := &record.Revision{
+		ChangedAt: time.Unix(0, int64(binary.LittleEndian.Uint64(entry[historyChangedOffset:]))).UTC(),
+	}
+
+	if entry[historyExistsOffset] == 0 {
+		return revision, nil
+	}
+
+	timeValue, err := decodeTime(entry[historyTimeOffset:historyStrOffset])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	revision.Version = int64(binary.LittleEndian.Uint64(entry[historyVersionOffset:]))
+	revision.Record = &record.Record{
+		Id:        int64(binary.LittleEndian.Uint64(entry[historyIDOffset:])),
+		Version:   revision.Version,
+		IntValue:  int64(binary.LittleEndian.Uint64(entry[historyIntOffset:])),
+		StrValue:  string(entry[historyStrOffset : len(entry)-4]),
+		BoolValue: entry[historyBoolOffset] != 0,
+		TimeValue: &timeValue,
+	}
+
+	return revision, nil
+}
+
+// validHistoryLength function verifies that length of history entry fits entry with StrValue up to maxStrLength bytes
+func validHistoryLength(length uint32, maxStrLength int) bool {
+	return length >= historyEntryOverhead && int64(length) <= historyEntryOverhead+int64(maxStrLength)
+}
+
+// validHistoryChecksum function verifies checksum of history entry
+func validHistoryChecksum(entry []byte) bool {
+	return crc32.Checksum(entry[:len(entry)-4], castagnoliTable) == binary.LittleEndian.Uint32(entry[len(entry)-4:])
+}
+
+// compactHistory function rewrites history file with ids of records changed by compaction
+// history of records removed by compaction is removed, their ids are taken by moved records
+// path of rewritten history file is returned, empty path when storage has no history file
+func compactHistory(fileStoragePath string, compacted *compaction, maxStrLength int) (string, error) {
+	historyFile, err := os.Open(historyFilePath(fileStoragePath))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer historyFile.Close()
+
+	compactedPath := historyFilePath(fileStoragePath) + compactFileSuffix
+
+	compactedFile, err := os.OpenFile(compactedPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer compactedFile.Close()
+
+	writer := bufio.NewWriter(compactedFile)
+
+	_, err = readHistoryEntries(historyFile, maxStrLength, func(pos int64, entry []byte) error {
+		id := int64(binary.LittleEndian.Uint64(entry[historyIDOffset:]))
+
+		if compacted.deletedIDs[id] {
+			return nil
+		}
+
+		if newID, ok := compacted.idMapping[id]; ok {
+			// corrupted entry stays corrupted
+			valid := validHistoryChecksum(entry)
+
+			binary.LittleEndian.PutUint64(entry[historyIDOffset:], uint64(newID))
+
+			if valid {
+				binary.LittleEndian.PutUint32(entry[len(entry)-4:], crc32.Checksum(entry[:len(entry)-4], castagnoliTable))
+			}
+		}
+
+		_, err := writer.Write(entry)
+		return errors.WithStack(err)
+	})
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if err == nil {
+		err = compactedFile.Sync()
+	}
+
+	if err != nil {
+		os.Remove(compactedPath)
+		return "", errors.WithStack(err)
+	}
+
+	return compactedPath, nil
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordHistory(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "history_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithHistory(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+	longValue := strings.Repeat("x", 100)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: longValue, TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.EditRecord(1, &record.Record{IntValue: 43, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.UpdateRecord(1, func(rec *record.Record) error {
+		rec.BoolValue = true
+		return nil
+	})
+	assert.NoError(t, err)
+
+	deleted, err := service.DeleteRecord(1)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	history, err := service.RecordHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 4)
+
+	// record did not exist before it was created
+	assert.Nil(t, history[0].Record)
+	assert.Equal(t, int64(0), history[0].Version)
+
+	expected := []record.Record{
+		{Id: 1, Version: 1, IntValue: 42, StrValue: longValue},
+		{Id: 1, Version: 2, IntValue: 43, StrValue: "foo"},
+		{Id: 1, Version: 3, IntValue: 43, StrValue: "foo", BoolValue: true},
+	}
+
+	for i, revision := range history[1:] {
+		assert.Equal(t, expected[i].Version, revision.Version)
+		assert.Equal(t, expected[i].Id, revision.Record.Id)
+		assert.Equal(t, expected[i].Version, revision.Record.Version)
+		assert.Equal(t, expected[i].IntValue, revision.Record.IntValue)
+		assert.Equal(t, expected[i].StrValue, revision.Record.StrValue)
+		assert.Equal(t, expected[i].BoolValue, revision.Record.BoolValue)
+		assert.Equal(t, testingTime, *revision.Record.TimeValue)
+		assert.False(t, revision.ChangedAt.Before(history[i].ChangedAt))
+	}
+
+	// record without changes has no history
+	history, err = service.RecordHistory(2)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestGetRecordAsOf(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "as_of_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithHistory(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		rec := record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime}
+
+		if i == 1 {
+			_, err = service.CreateRecord(&rec)
+		} else {
+			_, err = service.EditRecord(1, &rec)
+		}
+
+		assert.NoError(t, err)
+	}
+
+	history, err := service.RecordHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+
+	rec, err := service.GetRecordAsOf(1, history[0].ChangedAt.Add(-time.Nanosecond))
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+
+	for i, revision := range history[1:] {
+		rec, err := service.GetRecordAsOf(1, revision.ChangedAt.Add(-time.Nanosecond))
+		assert.NoError(t, err)
+		assert.NotNil(t, rec)
+		assert.Equal(t, int64(i+1), rec.IntValue)
+	}
+
+	rec, err = service.GetRecordAsOf(1, history[2].ChangedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), rec.IntValue)
+
+	// deleted record does not exist after delete
+	_, err = service.DeleteRecord(1)
+	assert.NoError(t, err)
+
+	rec, err = service.GetRecordAsOf(1, time.Now())
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+
+	rec, err = service.GetRecordAsOf(1, history[2].ChangedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), rec.IntValue)
+}
+
+func TestHistoryReopen(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "history_reopen_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service, err := NewService(tmpfile.Name(), WithHistory(true))
+	assert.NoError(t, err)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.EditRecord(1, &record.Record{IntValue: 43, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	service.Close()
+
+	// incomplete entry is left by interrupted write
+	historyFile, err := os.OpenFile(historyFilePath(tmpfile.Name()), os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	assert.NoError(t, err)
+	historyFile.Write([]byte{100, 0, 0, 0, 1, 2, 3})
+	historyFile.Close()
+
+	reopened := newTestService(t, tmpfile.Name(), WithHistory(true))
+
+	history, err := reopened.RecordHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, int64(42), history[1].Record.IntValue)
+
+	_, err = reopened.EditRecord(1, &record.Record{IntValue: 44, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	history, err = reopened.RecordHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, int64(43), history[2].Record.IntValue)
+}
+
+func TestHistoryDamagedLength(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "history_damaged_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service, err := NewService(tmpfile.Name(), WithHistory(true))
+	assert.NoError(t, err)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	service.Close()
+
+	// damaged length of entry would allocate 4 GiB
+	historyFile, err := os.OpenFile(historyFilePath(tmpfile.Name()), os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	assert.NoError(t, err)
+	historyFile.Write([]byte{0xf0, 0xff, 0xff, 0xff, 1, 2, 3})
+	historyFile.Close()
+
+	reopened := newTestService(t, tmpfile.Name(), WithHistory(true))
+
+	history, err := reopened.RecordHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	stat, err := os.Stat(historyFilePath(tmpfile.Name()))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(historyEntryOverhead), stat.Size())
+
+	// length of entry is limited by maximal length of StrValue
+	_, err = reopened.EditRecord(1, &record.Record{IntValue: 43, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	reopened.maxStrLength = 2
+
+	_, err = reopened.RecordHistory(1)
+	assert.ErrorIs(t, err, record.ErrCorruptRecord)
+}
+
+func TestCompactHistory(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "compact_history_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithHistory(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err = service.EditRecord(3, &record.Record{IntValue: 33, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.DeleteRecord(1)
+	assert.NoError(t, err)
+
+	idMapping, err := service.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int64{2: 1, 3: 2}, idMapping)
+
+	// history of removed record is removed, history of moved records follows new ids
+	history, err := service.RecordHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	history, err = service.RecordHistory(2)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, int64(2), history[1].Record.Id)
+	assert.Equal(t, int64(3), history[1].Record.IntValue)
+
+	history, err = service.RecordHistory(3)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	// history is appended after compaction
+	_, err = service.EditRecord(2, &record.Record{IntValue: 34, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	history, err = service.RecordHistory(2)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, int64(33), history[2].Record.IntValue)
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "history_disabled_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	_, err = service.RecordHistory(1)
+	assert.ErrorIs(t, err, record.ErrHistoryDisabled)
+
+	_, err = service.GetRecordAsOf(1, time.Now())
+	assert.ErrorIs(t, err, record.ErrHistoryDisabled)
+
+	_, err = os.Stat(historyFilePath(tmpfile.Name()))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,379 @@
+package storage
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"interviewtest/record"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Idempotency key of created record is appended to idempotency file next to storage file
+// entry is length(4) | createdAt(8) | id(8) | version(8) | fingerprint(32) | key | crc32c(4), createdAt is unix nanoseconds
+// fingerprint is sha256 of data of created record, key is not used for other record until time to live elapses
+const (
+	idempotencyFileSuffix        = ".idempotency"
+	idempotencyEntryOverhead     = 64
+	idempotencyCreatedOffset     = 4
+	idempotencyIDOffset          = 12
+	idempotencyVersionOffset     = 20
+	idempotencyFingerprintOffset = 28
+	idempotencyKeyOffset         = 60
+	// expired keys are removed after this number of appended keys
+	idempotencyPruneInterval = 1024
+)
+
+// idempotencyKey record created with idempotency key
+type idempotencyKey struct {
+	createdAt   int64
+	id          int64
+	version     int64
+	fingerprint [sha256.Size]byte
+}
+
+// idempotencyFilePath function returns path of idempotency file of storage file
+func idempotencyFilePath(fileStoragePath string) string {
+	return fileStoragePath + idempotencyFileSuffix
+}
+
+// openIdempotencyKeys method loads idempotency keys which are not expired
+// incomplete entry at the end of file is truncated, file is rewritten without expired keys
+func (service *service) openIdempotencyKeys() error {
+	idempotencyFile, err := os.OpenFile(idempotencyFilePath(service.storageFilePath), os.O_CREATE|os.O_RDWR|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.idempotencyFile = idempotencyFile
+
+	keys, size, err := readIdempotencyKeys(idempotencyFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	stat, err := idempotencyFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if stat.Size() > size {
+		log.Warnf("Incomplete idempotency key at %d truncated", size)
+
+		if err := idempotencyFile.Truncate(size); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	service.idempotencyKeys = keys
+
+	return service.pruneIdempotencyKeys()
+}
+
+// CreateRecordIdempotent method creates record once for idempotency key
+// record created before with the same key is not created again, its id and version are set into rec and false is returned
+// ErrIdempotencyKeyReused is returned when the key was used for record with different data
+func (service *service) CreateRecordIdempotent(key string, rec *record.Record) (bool, error) {
+	service.lockWrite()
+	defer service.unlockWrite()
+
+	if service.idempotencyFile == nil {
+		return false, errors.WithStack(record.ErrIdempotencyDisabled)
+	}
+
+	if len(key) == 0 || len(key) > record.MaxIdempotencyKeyLength {
+		return false, errors.Errorf("idempotency key must have 1 to %d bytes", record.MaxIdempotencyKeyLength)
+	}
+
+	if err := service.checkRecord(rec); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	fingerprint, err := recordFingerprint(rec)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	if created, ok := service.idempotencyKeys[key]; ok && !service.idempotencyExpired(created) {
+		if created.fingerprint != fingerprint {
+			return false, errors.Wrapf(record.ErrIdempotencyKeyReused, "key %q", key)
+		}
+
+		rec.Id = created.id
+		rec.Version = created.version
+
+		return false, nil
+	}
+
+	if _, err := service.createRecord(rec); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	// record is written before its key, key of record is lost when process crashes between the writes
+	created := idempotencyKey{createdAt: time.Now().UnixNano(), id: rec.Id, version: rec.Version, fingerprint: fingerprint}
+
+	if err := service.appendIdempotencyKey(key, created); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return true, nil
+}
+
+// appendIdempotencyKey method appends idempotency key of created record, expired keys are pruned periodically
+func (service *service) appendIdempotencyKey(key string, created idempotencyKey) error {
+	if _, err := service.idempotencyFile.Write(encodeIdempotencyKey(key, created)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if service.syncPolicy == SyncAlways {
+		if err := service.idempotencyFile.Sync(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	service.idempotencyKeys[key] = created
+	service.idempotencyAppends++
+
+	if service.idempotencyAppends < idempotencyPruneInterval {
+		return nil
+	}
+
+	return service.pruneIdempotencyKeys()
+}
+
+// pruneIdempotencyKeys method removes expired keys and rewrites idempotency file when any key was removed
+func (service *service) pruneIdempotencyKeys() error {
+	service.idempotencyAppends = 0
+	pruned := 0
+
+	for key, created := range service.idempotencyKeys {
+		if service.idempotencyExpired(created) {
+			delete(service.idempotencyKeys, key)
+			pruned++
+		}
+	}
+
+	if pruned == 0 {
+		return nil
+	}
+
+	log.Debugf("%d expired idempotency keys removed", pruned)
+
+	return service.rewriteIdempotencyKeys()
+}
+
+// rewriteIdempotencyKeys method replaces idempotency file by file with loaded keys
+func (service *service) rewriteIdempotencyKeys() error {
+	path := idempotencyFilePath(service.storageFilePath)
+
+	if err := writeIdempotencyKeys(path, service.idempotencyKeys); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return service.reopenIdempotencyFile()
+}
+
+// reopenIdempotencyFile method opens idempotency file again after the file was replaced
+func (service *service) reopenIdempotencyFile() error {
+	service.idempotencyFile.Close()
+
+	idempotencyFile, err := os.OpenFile(idempotencyFilePath(service.storageFilePath), os.O_CREATE|os.O_RDWR|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.idempotencyFile = idempotencyFile
+
+	return nil
+}
+
+// idempotencyExpired method reports whether time to live of idempotency key elapsed
+func (service *service) idempotencyExpired(created idempotencyKey) bool {
+	return time.Since(time.Unix(0, created.createdAt)) >= service.idempotencyTTL
+}
+
+// readIdempotencyKeys function reads idempotency keys and returns size of complete entries
+// corrupted entry is skipped, later entry of the same key replaces the earlier one
+func readIdempotencyKeys(idempotencyFile *os.File) (map[string]idempotencyKey, int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(idempotencyFile, 0, 1<<62))
+	length := make([]byte, 4)
+	keys := make(map[string]idempotencyKey)
+	pos := int64(0)
+
+	for {
+		if _, err := io.ReadFull(reader, length); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return keys, pos, nil
+		} else if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+
+		entryLength := binary.LittleEndian.Uint32(length)
+
+		// damaged length can not be skipped, rest of file is not readable
+		if entryLength < idempotencyEntryOverhead || entryLength > idempotencyEntryOverhead+record.MaxIdempotencyKeyLength {
+			return keys, pos, nil
+		}
+
+		entry := make([]byte, entryLength)
+		copy(entry, length)
+
+		if _, err := io.ReadFull(reader, entry[4:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return keys, pos, nil
+		} else if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+
+		pos += int64(entryLength)
+
+		if crc32.Checksum(entry[:len(entry)-4], castagnoliTable) != binary.LittleEndian.Uint32(entry[len(entry)-4:]) {
+			log.Warnf("Idempotency key at %d is corrupted", pos-int64(entryLength))
+			continue
+		}
+
+		key, created := decodeIdempotencyKey(entry)
+		keys[key] = created
+	}
+}
+
+// writeIdempotencyKeys function writes idempotency keys into new file which replaces file at path
+func writeIdempotencyKeys(path string, keys map[string]idempotencyKey) error {
+	rewrittenPath := path + compactFileSuffix
+
+	if err := writeIdempotencyFile(rewrittenPath, keys); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := os.Rename(rewrittenPath, path); err != nil {
+		os.Remove(rewrittenPath)
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// writeIdempotencyFile function writes idempotency keys into new synced file, file is removed when writing fails
+func writeIdempotencyFile(path string, keys map[string]idempotencyKey) error {
+	rewrittenFile, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer rewrittenFile.Close()
+
+	writer := bufio.NewWriter(rewrittenFile)
+
+	for key, created := range keys {
+		if _, err = writer.Write(encodeIdempotencyKey(key, created)); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if err == nil {
+		err = rewrittenFile.Sync()
+	}
+
+	if err != nil {
+		os.Remove(path)
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// compactIdempotencyKeys function returns idempotency keys with ids of records moved by compaction
+// keys of records removed by compaction are removed, their ids are taken by moved records
+func compactIdempotencyKeys(keys map[string]idempotencyKey, compacted *compaction) map[string]idempotencyKey {
+	compactedKeys := make(map[string]idempotencyKey, len(keys))
+
+	for key, created := range keys {
+		if compacted.deletedIDs[created.id] {
+			continue
+		}
+
+		if newID, ok := compacted.idMapping[created.id]; ok {
+			created.id = newID
+		}
+
+		compactedKeys[key] = created
+	}
+
+	return compactedKeys
+}
+
+// compactIdempotencyFile function writes idempotency keys with ids of records changed by compaction into new file
+// path of the new file is returned, empty path when storage file has no idempotency file
+func compactIdempotencyFile(fileStoragePath string, compacted *compaction) (string, error) {
+	idempotencyFile, err := os.Open(idempotencyFilePath(fileStoragePath))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	keys, _, err := readIdempotencyKeys(idempotencyFile)
+	idempotencyFile.Close()
+
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	compactedPath := idempotencyFilePath(fileStoragePath) + compactFileSuffix
+
+	if err := writeIdempotencyFile(compactedPath, compactIdempotencyKeys(keys, compacted)); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return compactedPath, nil
+}
+
+// encodeIdempotencyKey function encodes idempotency key of created record into entry
+func encodeIdempotencyKey(key string, created idempotencyKey) []byte {
+	entry := make([]byte, idempotencyEntryOverhead+len(key))
+
+	binary.LittleEndian.PutUint32(entry, uint32(len(entry)))
+	binary.LittleEndian.PutUint64(entry[idempotencyCreatedOffset:], uint64(created.createdAt))
+	binary.LittleEndian.PutUint64(entry[idempotencyIDOffset:], uint64(created.id))
+	binary.LittleEndian.PutUint64(entry[idempotencyVersionOffset:], uint64(created.version))
+	copy(entry[idempotencyFingerprintOffset:], created.fingerprint[:])
+	copy(entry[idempotencyKeyOffset:], key)
+	binary.LittleEndian.PutUint32(entry[len(entry)-4:], crc32.Checksum(entry[:len(entry)-4], castagnoliTable))
+
+	return entry
+}
+
+// decodeIdempotencyKey function decodes idempotency key of created record from entry
+func decodeIdempotencyKey(entry []byte) (string, idempotencyKey) {
+	created := idempotencyKey{
+		createdAt: int64(binary.LittleEndian.Uint64(entry[idempotencyCreatedOffset:])),
+		id:        int64(binary.LittleEndian.Uint64(entry[idempotencyIDOffset:])),
+		version:   int64(binary.LittleEndian.Uint64(entry[idempotencyVersionOffset:])),
+	}
+
+	copy(created.fingerprint[:], entry[idempotencyFingerprintOffset:idempotencyKeyOffset])
+
+	return string(entry[idempotencyKeyOffset : len(entry)-4]), created
+}
+
+// recordFingerprint function returns sha256 of data of record, id and version are not included
+func recordFingerprint(rec *record.Record) ([sha256.Size]byte, error) {
+	data := make([]byte, 25, 25+len(rec.StrValue))
+	binary.LittleEndian.PutUint64(data, uint64(rec.IntValue))
+
+	if rec.BoolValue {
+		data[8] = 1
+	}
+
+	if err := encodeTime(data[9:25], rec.TimeValue); err != nil {
+		return [sha256.Size]byte{}, errors.WithStack(err)
+	}
+
+	return sha256.Sum256(append(data, rec.StrValue...)), nil
+}
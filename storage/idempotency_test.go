@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRecordIdempotent(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "idempotent_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service, err := NewService(tmpfile.Name(), WithIdempotencyTTL(time.Hour))
+	assert.NoError(t, err)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	rec := record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}
+
+	created, err := service.CreateRecordIdempotent("first", &rec)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, int64(1), rec.Id)
+
+	// replayed request returns the first record
+	replayed := record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}
+
+	created, err = service.CreateRecordIdempotent("first", &replayed)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, int64(1), replayed.Id)
+	assert.Equal(t, int64(1), replayed.Version)
+
+	// key can not be used for other record
+	_, err = service.CreateRecordIdempotent("first", &record.Record{IntValue: 43, StrValue: "foo", TimeValue: &testingTime})
+	assert.ErrorIs(t, err, record.ErrIdempotencyKeyReused)
+
+	created, err = service.CreateRecordIdempotent("second", &record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	service.Close()
+
+	// keys are kept after reopen
+	reopened := newTestService(t, tmpfile.Name(), WithIdempotencyTTL(time.Hour))
+
+	replayed = record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}
+
+	created, err = reopened.CreateRecordIdempotent("second", &replayed)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, int64(2), replayed.Id)
+
+	// ids of moved records are changed by compaction, keys of removed records are removed
+	_, err = reopened.DeleteRecord(1)
+	assert.NoError(t, err)
+
+	_, err = reopened.Compact()
+	assert.NoError(t, err)
+
+	replayed = record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}
+
+	created, err = reopened.CreateRecordIdempotent("second", &replayed)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, int64(1), replayed.Id)
+
+	created, err = reopened.CreateRecordIdempotent("first", &record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.True(t, created)
+}
+
+func TestIdempotencyKeyExpired(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "idempotent_expired_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service, err := NewService(tmpfile.Name(), WithIdempotencyTTL(20*time.Millisecond))
+	assert.NoError(t, err)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	_, err = service.CreateRecordIdempotent("key", &record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+
+	rec := record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}
+
+	created, err := service.CreateRecordIdempotent("key", &rec)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, int64(2), rec.Id)
+
+	service.Close()
+
+	time.Sleep(30 * time.Millisecond)
+
+	// expired keys are removed from file when storage is opened
+	reopened := newTestService(t, tmpfile.Name(), WithIdempotencyTTL(20*time.Millisecond))
+	assert.Empty(t, reopened.idempotencyKeys)
+
+	stat, err := os.Stat(idempotencyFilePath(tmpfile.Name()))
+	assert.NoError(t, err)
+	assert.Zero(t, stat.Size())
+}
+
+func TestIdempotencyDisabled(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "idempotent_disabled_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	_, err = service.CreateRecordIdempotent("key", &record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.ErrorIs(t, err, record.ErrIdempotencyDisabled)
+
+	_, err = os.Stat(idempotencyFilePath(tmpfile.Name()))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,580 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"interviewtest/record"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Secondary index is sorted list of index keys and ids of records kept in memory
+// index is saved into index file next to storage file when service is closed and loaded when service is created,
+// index file is removed after loading, missing or stale index is rebuilt by scan of storage file
+// magic(8) | storage file size(8) | storage file modification time(8) | payload | crc32c(4)
+// payload of secondary index is count(8) | entries, entry is key value(8) | key nanoseconds(4) | id(8)
+const (
+	indexHeaderSize = 24
+	indexEntrySize  = 20
+)
+
+var indexMagic = []byte("IVTIDX\x00\x01")
+
+// indexKeys contains function returning index key of record for every field which can be indexed
+var indexKeys = map[string]func(rec *record.Record) record.IndexKey{
+	"IntValue": func(rec *record.Record) record.IndexKey {
+		return record.IntKey(rec.IntValue)
+	},
+	"TimeValue": func(rec *record.Record) record.IndexKey {
+		if rec.TimeValue == nil {
+			return record.IndexKey{}
+		}
+
+		return record.TimeKey(*rec.TimeValue)
+	},
+}
+
+type indexEntry struct {
+	key record.IndexKey
+	id  int64
+}
+
+// index structure with entries sorted by key and id and the same entries sorted by id
+// entries sorted by id are used for reading ids of wide range in chunks after id
+type index struct {
+	field   string
+	keyOf   func(rec *record.Record) record.IndexKey
+	entries []indexEntry
+	byID    []indexEntry
+}
+
+// indexFilePath function returns path of index file of field
+func indexFilePath(fileStoragePath, field string) string {
+	return fmt.Sprintf("%s.idx.%s", fileStoragePath, field)
+}
+
+// LookupRange method returns ids of records with value of indexed field in range
+// ids are sorted in ascending order, ErrNotIndexed is returned for field without index
+func (service *service) LookupRange(valueRange record.Range) ([]int64, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	idx, ok := service.indexes[valueRange.Field]
+	if !ok {
+		return nil, errors.Wrapf(record.ErrNotIndexed, "field %s", valueRange.Field)
+	}
+
+	return idx.lookup(valueRange.Min, valueRange.Max), nil
+}
+
+// LookupRangeAfter method returns at most limit ids greater than afterID of records with value of indexed field in range
+// ids are sorted in ascending order, so ids of range are read in chunks, chunk costs about limit entries of wide range
+// ErrNotIndexed is returned for field without index
+func (service *service) LookupRangeAfter(valueRange record.Range, afterID int64, limit int) ([]int64, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	idx, ok := service.indexes[valueRange.Field]
+	if !ok {
+		return nil, errors.Wrapf(record.ErrNotIndexed, "field %s", valueRange.Field)
+	}
+
+	return idx.lookupAfter(valueRange.Min, valueRange.Max, afterID, limit), nil
+}
+
+// LookupOrdered method returns at most limit entries of records with value of indexed field in range
+// entries are ordered by key and id, in descending order when descending is true, and follow entry after when it is not nil
+// ErrNotIndexed is returned for field without index
+func (service *service) LookupOrdered(valueRange record.Range, after *record.IndexEntry, descending bool, limit int) ([]record.IndexEntry, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	idx, ok := service.indexes[valueRange.Field]
+	if !ok {
+		return nil, errors.Wrapf(record.ErrNotIndexed, "field %s", valueRange.Field)
+	}
+
+	return idx.lookupOrdered(valueRange.Min, valueRange.Max, after, descending, limit), nil
+}
+
+// RebuildIndexFiles function builds index files of indexes enabled by options from storage file (offline reindex)
+// All existing index files are removed, function must not be used when storage file is opened by running service
+func RebuildIndexFiles(fileStoragePath string, options ...Option) error {
+	if err := removeIndexFiles(fileStoragePath); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// missing indexes are rebuilt when service is created and saved when it is closed
+	storageService, err := NewService(fileStoragePath, options...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	storageService.Close()
+	return nil
+}
+
+// removeIndexFiles function removes all index files of storage file
+func removeIndexFiles(fileStoragePath string) error {
+	indexFiles, err := filepath.Glob(indexFilePath(fileStoragePath, "*"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, path := range indexFiles {
+		if err := os.Remove(path); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// openIndexes method loads secondary indexes and full-text index, missing or stale indexes are rebuilt
+func (service *service) openIndexes() error {
+	service.indexes = make(map[string]*index)
+
+	// unknown field is rejected before any index is opened, opened index is saved on close
+	for _, field := range service.indexedFields {
+		if _, ok := indexKeys[field]; !ok {
+			return errors.Errorf("field %s can not be indexed", field)
+		}
+	}
+
+	if err := service.openSearchIndex(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(service.indexedFields) == 0 {
+		return nil
+	}
+
+	stat, err := service.storageFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var rebuild []*index
+
+	for _, field := range service.indexedFields {
+		idx := &index{field: field, keyOf: indexKeys[field]}
+		service.indexes[field] = idx
+
+		path := indexFilePath(service.storageFilePath, field)
+
+		if err := idx.load(path, stat); err != nil {
+			log.Infof("Index of %s is rebuilt: %s", field, err)
+			rebuild = append(rebuild, idx)
+		}
+
+		// index in memory is changed by every write, file is written again on close
+		os.Remove(path)
+	}
+
+	if err := rebuildIndexes(service.storageFile, rebuild); err != nil {
+		// incomplete index must not be saved on close
+		service.indexes = nil
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// rebuildIndexes function builds indexes by scan of all records in storage file
+func rebuildIndexes(storageFile *os.File, indexes []*index) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	for _, idx := range indexes {
+		idx.entries = nil
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(storageFile, headerSize)
+	slot := buffer[:]
+
+	for pos := int64(headerSize); ; pos += recordSize {
+		if _, err := io.ReadFull(reader, slot); err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		rec := indexedRecord(slot)
+		if rec == nil {
+			continue
+		}
+
+		for _, idx := range indexes {
+			idx.entries = append(idx.entries, indexEntry{key: idx.keyOf(rec), id: slotID(pos)})
+		}
+	}
+
+	for _, idx := range indexes {
+		sort.Slice(idx.entries, func(i, j int) bool { return idx.less(idx.entries[i], idx.entries[j].key, idx.entries[j].id) })
+		idx.sortByID()
+	}
+
+	return nil
+}
+
+// saveIndexes method writes all indexes into index files
+func (service *service) saveIndexes() error {
+	if len(service.indexes) == 0 && service.searchIndex == nil {
+		return nil
+	}
+
+	stat, err := service.storageFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if service.searchIndex != nil {
+		if err := service.searchIndex.save(indexFilePath(service.storageFilePath, searchIndexField), stat); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, idx := range service.indexes {
+		if err := idx.save(indexFilePath(service.storageFilePath, idx.field), stat); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// readIndexedRecord method reads record stored in slot at position for update of indexes
+func (service *service) readIndexedRecord(pos int64) (*record.Record, error) {
+	if len(service.indexes) == 0 {
+		return nil, nil
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	slot := buffer[:]
+
+	if _, err := service.readAt(slot, pos); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return indexedRecord(slot), nil
+}
+
+// updateIndexes method replaces index entries of previous record in slot by entries of new record
+func (service *service) updateIndexes(id int64, previous, current *record.Record) {
+	for _, idx := range service.indexes {
+		if previous != nil {
+			idx.remove(idx.keyOf(previous), id)
+		}
+
+		if current != nil {
+			idx.insert(idx.keyOf(current), id)
+		}
+	}
+}
+
+// indexedRecord function decodes fields which can be indexed from slot
+// nil is returned for deleted or corrupted record
+func indexedRecord(slot []byte) *record.Record {
+	if binary.LittleEndian.Uint64(slot) == 0 || !validChecksum(slot) {
+		return nil
+	}
+
+	timeValue, err := decodeTime(slot[timeValueOffset:])
+	if err != nil {
+		return nil
+	}
+
+	return &record.Record{
+		Id:        int64(binary.LittleEndian.Uint64(slot)),
+		IntValue:  int64(binary.LittleEndian.Uint64(slot[intValueOffset:])),
+		TimeValue: &timeValue,
+	}
+}
+
+// less method compares index entry with key and id
+func (idx *index) less(entry indexEntry, key record.IndexKey, id int64) bool {
+	if compared := entry.key.Compare(key); compared != 0 {
+		return compared < 0
+	}
+
+	return entry.id < id
+}
+
+// search method returns position of first entry which is not lower than key and id
+func (idx *index) search(key record.IndexKey, id int64) int {
+	return sort.Search(len(idx.entries), func(i int) bool { return !idx.less(idx.entries[i], key, id) })
+}
+
+// searchID method returns position of first entry sorted by id which is not lower than id
+func (idx *index) searchID(id int64) int {
+	return sort.Search(len(idx.byID), func(i int) bool { return idx.byID[i].id >= id })
+}
+
+// bounds method returns positions of first entry with key in inclusive range and first entry after range
+func (idx *index) bounds(min, max *record.IndexKey) (int, int) {
+	start, end := 0, len(idx.entries)
+
+	if min != nil {
+		start = idx.search(*min, 0)
+	}
+
+	if max != nil {
+		end = sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].key.Compare(*max) > 0 })
+	}
+
+	if end < start {
+		end = start
+	}
+
+	return start, end
+}
+
+// sortByID method builds entries sorted by id from entries sorted by key
+func (idx *index) sortByID() {
+	idx.byID = append(make([]indexEntry, 0, len(idx.entries)), idx.entries...)
+	sort.Slice(idx.byID, func(i, j int) bool { return idx.byID[i].id < idx.byID[j].id })
+}
+
+func (idx *index) insert(key record.IndexKey, id int64) {
+	entry := indexEntry{key: key, id: id}
+
+	idx.entries = insertEntry(idx.entries, idx.search(key, id), entry)
+	idx.byID = insertEntry(idx.byID, idx.searchID(id), entry)
+}
+
+func (idx *index) remove(key record.IndexKey, id int64) {
+	entry := indexEntry{key: key, id: id}
+
+	if i := idx.search(key, id); i < len(idx.entries) && idx.entries[i] == entry {
+		idx.entries = append(idx.entries[:i], idx.entries[i+1:]...)
+	}
+
+	if i := idx.searchID(id); i < len(idx.byID) && idx.byID[i] == entry {
+		idx.byID = append(idx.byID[:i], idx.byID[i+1:]...)
+	}
+}
+
+func insertEntry(entries []indexEntry, i int, entry indexEntry) []indexEntry {
+	entries = append(entries, indexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+
+	return entries
+}
+
+// lookup method returns sorted ids of entries with key in inclusive range
+func (idx *index) lookup(min, max *record.IndexKey) []int64 {
+	start, end := idx.bounds(min, max)
+
+	ids := make([]int64, 0, end-start)
+
+	for _, entry := range idx.entries[start:end] {
+		ids = append(ids, entry.id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// lookupAfter method returns at most limit smallest ids greater than afterID of entries with key in range
+// entries sorted by id are walked from afterID for wide range, walk visits about limit*len/count entries for range of count entries
+// narrow range (count*count <= limit*len) is collected from entries sorted by key, it visits count entries,
+// so reading of all ids of any range in chunks visits about len entries
+func (idx *index) lookupAfter(min, max *record.IndexKey, afterID int64, limit int) []int64 {
+	if limit < 1 {
+		return []int64{}
+	}
+
+	start, end := idx.bounds(min, max)
+
+	if count := end - start; count*count <= limit*len(idx.entries) {
+		return collectAfter(idx.entries[start:end], afterID, limit)
+	}
+
+	ids := make([]int64, 0, limit)
+
+	for _, entry := range idx.byID[idx.searchID(afterID+1):] {
+		if len(ids) == limit {
+			break
+		}
+
+		if (min == nil || entry.key.Compare(*min) >= 0) && (max == nil || entry.key.Compare(*max) <= 0) {
+			ids = append(ids, entry.id)
+		}
+	}
+
+	return ids
+}
+
+// lookupOrdered method returns at most limit entries with key in range following entry after in order of key and id
+func (idx *index) lookupOrdered(min, max *record.IndexKey, after *record.IndexEntry, descending bool, limit int) []record.IndexEntry {
+	start, end := idx.bounds(min, max)
+
+	if after != nil {
+		// entry after could be removed from index, following entries start at its position
+		pos := idx.search(after.Key, after.ID)
+
+		if descending && pos < end {
+			end = pos
+		} else if !descending && pos < len(idx.entries) && idx.entries[pos] == (indexEntry{key: after.Key, id: after.ID}) {
+			pos++
+		}
+
+		if !descending && pos > start {
+			start = pos
+		}
+	}
+
+	entries := make([]record.IndexEntry, 0)
+
+	for i := 0; i < limit && start < end; i++ {
+		entry := idx.entries[start]
+
+		if descending {
+			end--
+			entry = idx.entries[end]
+		} else {
+			start++
+		}
+
+		entries = append(entries, record.IndexEntry{Key: entry.key, ID: entry.id})
+	}
+
+	return entries
+}
+
+// collectAfter function returns at most limit smallest ids greater than afterID of entries
+// collected ids are sorted and cut to limit whenever twice as many ids are collected
+func collectAfter(entries []indexEntry, afterID int64, limit int) []int64 {
+	ids := make([]int64, 0, 2*limit)
+	bound := int64(math.MaxInt64)
+
+	keepSmallest := func() {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		if len(ids) >= limit {
+			ids = ids[:limit]
+			bound = ids[limit-1]
+		}
+	}
+
+	for _, entry := range entries {
+		// id greater than the largest of limit kept ids is not in result
+		if entry.id <= afterID || entry.id > bound {
+			continue
+		}
+
+		ids = append(ids, entry.id)
+
+		if len(ids) == 2*limit {
+			keepSmallest()
+		}
+	}
+
+	keepSmallest()
+
+	return ids
+}
+
+// save method writes index into index file
+func (idx *index) save(path string, stat os.FileInfo) error {
+	payload := make([]byte, 8+len(idx.entries)*indexEntrySize)
+	binary.LittleEndian.PutUint64(payload, uint64(len(idx.entries)))
+
+	for i, entry := range idx.entries {
+		entryBytes := payload[8+i*indexEntrySize:]
+		binary.LittleEndian.PutUint64(entryBytes, uint64(entry.key.Value))
+		binary.LittleEndian.PutUint32(entryBytes[8:], uint32(entry.key.Nanos))
+		binary.LittleEndian.PutUint64(entryBytes[12:], uint64(entry.id))
+	}
+
+	return writeIndexFile(path, indexMagic, stat, payload)
+}
+
+// load method reads index from index file, error is returned for missing, damaged or stale index file
+func (idx *index) load(path string, stat os.FileInfo) error {
+	payload, err := readIndexFile(path, indexMagic, stat)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(payload) < 8 || uint64(len(payload)) != 8+binary.LittleEndian.Uint64(payload)*indexEntrySize {
+		return errors.New("invalid size of index file")
+	}
+
+	idx.entries = make([]indexEntry, binary.LittleEndian.Uint64(payload))
+
+	for i := range idx.entries {
+		entryBytes := payload[8+i*indexEntrySize:]
+		idx.entries[i] = indexEntry{
+			key: record.IndexKey{
+				Value: int64(binary.LittleEndian.Uint64(entryBytes)),
+				Nanos: int32(binary.LittleEndian.Uint32(entryBytes[8:])),
+			},
+			id: int64(binary.LittleEndian.Uint64(entryBytes[12:])),
+		}
+	}
+
+	idx.sortByID()
+
+	return nil
+}
+
+// writeIndexFile function writes payload of index into index file
+// size and modification time of storage file are stored for staleness check
+func writeIndexFile(path string, magic []byte, stat os.FileInfo, payload []byte) error {
+	buffer := make([]byte, indexHeaderSize+len(payload)+4)
+	copy(buffer, magic)
+	binary.LittleEndian.PutUint64(buffer[8:], uint64(stat.Size()))
+	binary.LittleEndian.PutUint64(buffer[16:], uint64(stat.ModTime().UnixNano()))
+	copy(buffer[indexHeaderSize:], payload)
+	binary.LittleEndian.PutUint32(buffer[len(buffer)-4:], crc32.Checksum(buffer[:len(buffer)-4], castagnoliTable))
+
+	tmpPath := path + ".tmp"
+
+	if err := os.WriteFile(tmpPath, buffer, os.ModePerm); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmpPath, path))
+}
+
+// readIndexFile function reads payload of index from index file
+// error is returned for missing, damaged or stale index file
+func readIndexFile(path string, magic []byte, stat os.FileInfo) ([]byte, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(buffer) < indexHeaderSize+4 || !bytes.Equal(buffer[:len(magic)], magic) {
+		return nil, errors.New("invalid index file")
+	}
+
+	if crc32.Checksum(buffer[:len(buffer)-4], castagnoliTable) != binary.LittleEndian.Uint32(buffer[len(buffer)-4:]) {
+		return nil, errors.New("index file checksum mismatch")
+	}
+
+	if int64(binary.LittleEndian.Uint64(buffer[8:])) != stat.Size() || int64(binary.LittleEndian.Uint64(buffer[16:])) != stat.ModTime().UnixNano() {
+		return nil, errors.New("storage file was changed after index was saved")
+	}
+
+	return buffer[indexHeaderSize : len(buffer)-4], nil
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func intRange(min, max int64) record.Range {
+	minKey, maxKey := record.IntKey(min), record.IntKey(max)
+
+	return record.Range{Field: "IntValue", Min: &minKey, Max: &maxKey}
+}
+
+func TestIndexMaintainedByWrites(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "index_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue", "TimeValue"), WithSlotReuse(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for _, intValue := range []int64{50, 10, 40, 20, 30} {
+		timeValue := testingTime.Add(time.Duration(intValue) * time.Minute)
+
+		_, err := service.CreateRecord(&record.Record{IntValue: intValue, StrValue: "foo", TimeValue: &timeValue})
+		assert.NoError(t, err)
+	}
+
+	ids, err := service.LookupRange(intRange(20, 40))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 4, 5}, ids)
+
+	_, err = service.EditRecord(3, &record.Record{Id: 3, IntValue: 99, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.DeleteRecord(4)
+	assert.NoError(t, err)
+
+	ids, err = service.LookupRange(intRange(20, 40))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{5}, ids)
+
+	// deleted slot is reused
+	_, err = service.CreateRecord(&record.Record{IntValue: 25, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	ids, err = service.LookupRange(intRange(20, 40))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, 5}, ids)
+
+	minTime := record.TimeKey(testingTime.Add(30 * time.Minute))
+
+	ids, err = service.LookupRange(record.Range{Field: "TimeValue", Min: &minTime})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 5}, ids)
+
+	_, err = service.LookupRange(record.Range{Field: "StrValue"})
+	assert.ErrorIs(t, err, record.ErrNotIndexed)
+}
+
+func TestLookupRangeAfter(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "index_after_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for _, intValue := range []int64{30, 10, 20, 30, 50, 20, 40} {
+		_, err := service.CreateRecord(&record.Record{IntValue: intValue, StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	lookupPages := func(valueRange record.Range, limit int) [][]int64 {
+		var pages [][]int64
+
+		for afterID := int64(0); ; {
+			ids, err := service.LookupRangeAfter(valueRange, afterID, limit)
+			assert.NoError(t, err)
+
+			pages = append(pages, ids)
+
+			if len(ids) < limit {
+				return pages
+			}
+
+			afterID = ids[len(ids)-1]
+		}
+	}
+
+	// wide range is read by walk of entries sorted by id, narrow range from entries sorted by key
+	assert.Equal(t, [][]int64{{1, 3}, {4, 6}, {7}}, lookupPages(intRange(20, 40), 2))
+	assert.Equal(t, [][]int64{{1}, {4}, {}}, lookupPages(intRange(30, 30), 1))
+
+	_, err = service.EditRecord(4, &record.Record{Id: 4, IntValue: 60, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.DeleteRecord(6)
+	assert.NoError(t, err)
+
+	assert.Equal(t, [][]int64{{1, 3}, {7}}, lookupPages(intRange(20, 40), 2))
+
+	_, err = service.LookupRangeAfter(record.Range{Field: "StrValue"}, 0, 2)
+	assert.ErrorIs(t, err, record.ErrNotIndexed)
+}
+
+func TestLookupOrdered(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "index_ordered_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for _, intValue := range []int64{30, 10, 20, 30, 50} {
+		_, err := service.CreateRecord(&record.Record{IntValue: intValue, StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	entryIDs := func(entries []record.IndexEntry) []int64 {
+		ids := make([]int64, 0)
+
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+
+		return ids
+	}
+
+	entries, err := service.LookupOrdered(intRange(20, 50), nil, false, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 1, 4}, entryIDs(entries))
+
+	entries, err = service.LookupOrdered(intRange(20, 50), &entries[1], false, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, 5}, entryIDs(entries))
+
+	entries, err = service.LookupOrdered(intRange(10, 30), &record.IndexEntry{Key: record.IntKey(30), ID: 4}, true, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 3}, entryIDs(entries))
+
+	// entry of changed record is not in index, lookup continues at its position
+	_, err = service.EditRecord(1, &record.Record{Id: 1, IntValue: 40, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	entries, err = service.LookupOrdered(record.Range{Field: "IntValue"}, &record.IndexEntry{Key: record.IntKey(30), ID: 1}, false, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, 1, 5}, entryIDs(entries))
+
+	_, err = service.LookupOrdered(record.Range{Field: "TimeValue"}, nil, false, 1)
+	assert.ErrorIs(t, err, record.ErrNotIndexed)
+}
+
+func TestIndexPersistedAndRebuilt(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "persisted_index_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := int64(1); i <= 3; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: i * 10, StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	service.Close()
+
+	_, err = os.Stat(indexFilePath(tmpfile.Name(), "IntValue"))
+	assert.NoError(t, err)
+
+	// index file is loaded and removed until service is closed
+	service = newTestService(t, tmpfile.Name(), WithIndexes("IntValue"))
+
+	_, err = os.Stat(indexFilePath(tmpfile.Name(), "IntValue"))
+	assert.True(t, os.IsNotExist(err))
+
+	ids, err := service.LookupRange(intRange(0, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+
+	service.Close()
+
+	// storage file is changed by service without indexes, index file is stale
+	service = newTestService(t, tmpfile.Name())
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 40, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	service.Close()
+
+	service = newTestService(t, tmpfile.Name(), WithIndexes("IntValue"))
+
+	ids, err = service.LookupRange(intRange(0, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4}, ids)
+}
+
+func TestIndexRebuiltByCompaction(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "compact_index_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := int64(1); i <= 4; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: i * 10, StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err = service.DeleteRecord(2)
+	assert.NoError(t, err)
+
+	_, err = service.Compact()
+	assert.NoError(t, err)
+
+	ids, err := service.LookupRange(intRange(30, 40))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, ids)
+}
+
+func TestRebuildIndexFiles(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "reindex_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 3; i++ {
+		timeValue := testingTime.Add(time.Duration(i) * time.Hour)
+
+		_, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &timeValue})
+		assert.NoError(t, err)
+	}
+
+	service.Close()
+
+	err = RebuildIndexFiles(tmpfile.Name(), WithIndexes("TimeValue"))
+	assert.NoError(t, err)
+
+	stat, err := os.Stat(tmpfile.Name())
+	assert.NoError(t, err)
+
+	idx := &index{field: "TimeValue", keyOf: indexKeys["TimeValue"]}
+	assert.NoError(t, idx.load(indexFilePath(tmpfile.Name(), "TimeValue"), stat))
+	assert.Len(t, idx.entries, 3)
+
+	err = RebuildIndexFiles(tmpfile.Name(), WithIndexes("StrValue"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"encoding/binary"
+	"interviewtest/record"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Problems of record slot reported by integrity check
+const (
+	problemMisplacedRecord = "stored id does not match slot"
+	problemChecksum        = "checksum mismatch"
+	problemHeapEntry       = "damaged heap entry of StrValue"
+)
+
+// CheckIntegrity method scans whole file and reports slots with misplaced or corrupted record
+// record is misplaced when id stored in slot differs from id given by position of slot,
+// such record is not found by id
+func (service *service) CheckIntegrity() ([]record.IntegrityIssue, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	issues := make([]record.IntegrityIssue, 0)
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(service.storageFile, headerSize)
+	slot := buffer[:]
+
+	for id := int64(1); ; id++ {
+		if _, err := io.ReadFull(reader, slot); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		storedID := int64(binary.LittleEndian.Uint64(slot))
+		issue := record.IntegrityIssue{ID: id, StoredID: storedID}
+
+		switch {
+		case !validChecksum(slot):
+			issue.Problem = problemChecksum
+		case storedID != 0 && storedID != id:
+			issue.Problem = problemMisplacedRecord
+		case storedID != 0 && !validHeapEntry(slot, service.heapFile):
+			issue.Problem = problemHeapEntry
+		default:
+			continue
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// CheckFile function runs integrity check of storage file
+// Function must not be used when storage file is opened by running service
+func CheckFile(fileStoragePath string) ([]record.IntegrityIssue, error) {
+	service, err := NewService(fileStoragePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer service.Close()
+
+	return service.CheckIntegrity()
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"encoding/binary"
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "integrity_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 4; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	issues, err := service.CheckIntegrity()
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	_, err = service.DeleteRecord(4)
+	assert.NoError(t, err)
+
+	// record with id 3 written into slot of record 1
+	writeStoredID(t, tmpfile, 1, 3)
+
+	// flip bit in StrValue of second record
+	_, err = tmpfile.WriteAt([]byte{'g'}, slotPosition(2)+strValueOffset)
+	assert.NoError(t, err)
+
+	issues, err = service.CheckIntegrity()
+	assert.NoError(t, err)
+	assert.Equal(t, []record.IntegrityIssue{
+		{ID: 1, StoredID: 3, Problem: problemMisplacedRecord},
+		{ID: 2, StoredID: 2, Problem: problemChecksum},
+	}, issues)
+}
+
+func TestCheckFile(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "integrity_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service, err := NewService(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 1, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	service.Close()
+
+	writeStoredID(t, tmpfile, 1, 5)
+
+	issues, err := CheckFile(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, []record.IntegrityIssue{{ID: 1, StoredID: 5, Problem: problemMisplacedRecord}}, issues)
+}
+
+// writeStoredID function rewrites id stored in slot, checksum of record does not cover id
+func writeStoredID(t *testing.T, file *os.File, id, storedID int64) {
+	if _, err := file.WriteAt(binary.LittleEndian.AppendUint64(nil, uint64(storedID)), slotPosition(id)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"interviewtest/record"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const migrateFileSuffix = ".migrate"
+
+// Records of headerless version 0 file written by first version of application
+// id(8) | IntValue(8) | StrValue(64) | BoolValue(1) | TimeValue(16) | '\n'(1)
+const (
+	v0RecordSize      = 98
+	v0StrValueOffset  = 16
+	v0BoolValueOffset = 80
+	v0TimeValueOffset = 81
+)
+
+// MigrateFile function upgrades storage file to current format version
+// Migrated file is written to target path, when target path is the same as source path
+// file is migrated in place and original file is kept with version suffix
+// Function must not be used when storage file is opened by running service
+func MigrateFile(sourcePath, targetPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer source.Close()
+
+	header, err := readFileHeader(source)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if header == nil {
+		return errors.Errorf("storage file %s is empty", sourcePath)
+	}
+
+	// formats between version 0 and current version were never released
+	if header.Version != 0 && header.Version != formatVersion {
+		return errors.Wrapf(ErrUnsupportedVersion, "version %d", header.Version)
+	}
+
+	if header.Version == formatVersion && sourcePath == targetPath {
+		log.Infof("Storage file %s is in current format version %d", sourcePath, formatVersion)
+		return nil
+	}
+
+	tmpPath := targetPath + migrateFileSuffix
+
+	if err := migrateInto(source, header, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return errors.WithStack(err)
+	}
+
+	// heap file is not changed by migration, it is copied for new target file
+	if header.Version == formatVersion && sourcePath != targetPath {
+		if err := copyFile(heapFilePath(sourcePath, header.HeapGeneration), heapFilePath(targetPath, header.HeapGeneration)); err != nil {
+			os.Remove(tmpPath)
+			return errors.WithStack(err)
+		}
+	}
+
+	if sourcePath == targetPath {
+		backupPath := fmt.Sprintf("%s.v%d.bak", sourcePath, header.Version)
+
+		if err := os.Rename(sourcePath, backupPath); err != nil {
+			os.Remove(tmpPath)
+			return errors.WithStack(err)
+		}
+
+		log.Infof("Original storage file is kept as %s", backupPath)
+	}
+
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		return errors.WithStack(err)
+	}
+
+	log.Infof("Storage file %s migrated from version %d to version %d", sourcePath, header.Version, formatVersion)
+	return nil
+}
+
+// migrateInto function upgrades all record slots of source file and writes them into new file
+func migrateInto(source *os.File, header *fileHeader, targetPath string) error {
+	sourceHeaderSize, sourceRecordSize := headerSize, recordSize
+
+	if header.Version == 0 {
+		sourceHeaderSize, sourceRecordSize = 0, v0RecordSize
+	}
+
+	if _, err := source.Seek(int64(sourceHeaderSize), io.SeekStart); err != nil {
+		return errors.WithStack(err)
+	}
+
+	target, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer target.Close()
+
+	targetHeader := newFileHeader()
+	targetHeader.HeapGeneration = header.HeapGeneration
+
+	if !header.CreatedAt.IsZero() {
+		targetHeader.CreatedAt = header.CreatedAt
+	}
+
+	if _, err := target.Write(targetHeader.encode()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	slot := make([]byte, sourceRecordSize)
+
+	for {
+		if _, err := io.ReadFull(source, slot); err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			log.Warn("Incomplete record at the end of storage file discarded")
+			break
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		upgradedSlot := slot
+
+		if header.Version == 0 {
+			upgradedSlot = upgradeFromV0(slot)
+		}
+
+		if _, err := target.Write(upgradedSlot); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return errors.WithStack(target.Sync())
+}
+
+// upgradeFromV0 function converts record slot of headerless file into slot of current format
+// every record gets the first version, deleted record with data is marked as deleted at time of migration
+// all-zero slot skipped by edit after end of file is empty slot with zero version, which can not be restored
+// record with TimeValue which can not be decoded by time.UnmarshalBinary stays in file as corrupt record
+func upgradeFromV0(slot []byte) []byte {
+	upgradedSlot := make([]byte, recordSize)
+
+	if bytes.Equal(slot[:v0RecordSize-1], make([]byte, v0RecordSize-1)) {
+		// zero TimeValue is always encodable
+		encodeRecord(upgradedSlot, &record.Record{TimeValue: &time.Time{}}, 0)
+		return upgradedSlot
+	}
+
+	id := int64(binary.LittleEndian.Uint64(slot))
+
+	// length of encoded time is given by its version byte, zero bytes of padding are ignored
+	// second version of time.MarshalBinary stores offset with seconds
+	timeBytes := slot[v0TimeValueOffset : v0TimeValueOffset+16]
+	timeLength := 15
+
+	if timeBytes[0] == 2 {
+		timeLength = 16
+	}
+
+	var timeValue time.Time
+
+	valid := timeValue.UnmarshalBinary(timeBytes[:timeLength]) == nil
+
+	rec := &record.Record{
+		Id:        id,
+		Version:   1,
+		IntValue:  int64(binary.LittleEndian.Uint64(slot[8:])),
+		StrValue:  string(bytes.TrimRight(slot[v0StrValueOffset:v0BoolValueOffset], "\x00")),
+		BoolValue: slot[v0BoolValueOffset] != 0,
+		TimeValue: &timeValue,
+	}
+
+	if valid && encodeRecord(upgradedSlot, rec, 0) != nil {
+		valid = false
+	}
+
+	if !valid {
+		rec.TimeValue = &time.Time{}
+		encodeRecord(upgradedSlot, rec, 0)
+
+		checksum := binary.LittleEndian.Uint32(upgradedSlot[checksumOffset:])
+		binary.LittleEndian.PutUint32(upgradedSlot[checksumOffset:], ^checksum)
+	}
+
+	if id == 0 {
+		binary.LittleEndian.PutUint64(upgradedSlot[deletedAtOffset:], uint64(time.Now().UnixNano()))
+	}
+
+	return upgradedSlot
+}
+
+// copyFile function copies content of file into new file
+func copyFile(sourcePath, targetPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer target.Close()
+
+	if _, err := io.Copy(target, source); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(target.Sync())
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServiceWritesHeader(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "header_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	newTestService(t, tmpfile.Name())
+
+	header, err := readFileHeader(tmpfile)
+	assert.NoError(t, err)
+	assert.NotNil(t, header)
+	assert.Equal(t, uint32(formatVersion), header.Version)
+	assert.Equal(t, uint32(recordSize), header.RecordSize)
+	assert.WithinDuration(t, time.Now(), header.CreatedAt, time.Minute)
+}
+
+func TestNewServiceInvalidHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      func() []byte
+		expectedErr error
+	}{{
+		name: "Damaged header",
+		header: func() []byte {
+			header := newFileHeader().encode()
+			header[20] ^= 0xff
+			return header
+		},
+		expectedErr: ErrInvalidHeader,
+	}, {
+		name: "Newer format version",
+		header: func() []byte {
+			header := newFileHeader()
+			header.Version = formatVersion + 1
+			return header.encode()
+		},
+		expectedErr: ErrUnsupportedVersion,
+	}, {
+		name: "Unreleased format version",
+		header: func() []byte {
+			header := newFileHeader()
+			header.Version = formatVersion - 1
+			return header.encode()
+		},
+		expectedErr: ErrUnsupportedVersion,
+	}, {
+		name: "Different record size",
+		header: func() []byte {
+			header := newFileHeader()
+			header.RecordSize = recordSize + 1
+			return header.encode()
+		},
+		expectedErr: ErrInvalidHeader,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpfile, err := os.CreateTemp("", "invalid_header_records.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			testtools.CleanupFiles(t, tmpfile.Name())
+			defer tmpfile.Close()
+
+			tmpfile.Write(tt.header())
+
+			_, err = NewService(tmpfile.Name())
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestMigrateFromV0(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "v0_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	records := []record.Record{
+		{Id: 1, IntValue: 42, StrValue: "foo", BoolValue: true, TimeValue: &testingTime},
+		{Id: 0, IntValue: 90, StrValue: "deleted", BoolValue: false, TimeValue: &testingTime},
+		{Id: 3, IntValue: 99, StrValue: "foo99", BoolValue: false, TimeValue: &testingTime},
+	}
+
+	for i := range records {
+		tmpfile.Write(encodeV0Record(&records[i]))
+	}
+
+	// slot skipped by edit after end of file is all-zero, record with damaged TimeValue is corrupt
+	tmpfile.Write(make([]byte, 98))
+
+	corruptSlot := encodeV0Record(&record.Record{Id: 5, IntValue: 7, StrValue: "bar", TimeValue: &testingTime})
+	corruptSlot[81] = 0xff
+	tmpfile.Write(corruptSlot)
+
+	_, err = NewService(tmpfile.Name())
+	assert.ErrorIs(t, err, ErrMigrationRequired)
+
+	service := newTestService(t, tmpfile.Name(), WithAutoMigrate(true))
+
+	for _, expected := range []record.Record{records[0], records[2]} {
+		rec, err := service.GetRecord(expected.Id)
+		assert.NoError(t, err)
+		assert.NotNil(t, rec)
+		assert.Equal(t, int64(1), rec.Version)
+		assert.Equal(t, expected.IntValue, rec.IntValue)
+		assert.Equal(t, expected.StrValue, rec.StrValue)
+		assert.Equal(t, expected.BoolValue, rec.BoolValue)
+		assert.Equal(t, testingTime, *rec.TimeValue)
+	}
+
+	deletedRecord, err := service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Nil(t, deletedRecord)
+
+	corruptRecord, err := service.GetRecord(5)
+	assert.Nil(t, corruptRecord)
+	assert.ErrorIs(t, err, record.ErrCorruptRecord)
+
+	// deleted record is marked as deleted at time of migration, empty slot can not be restored
+	var deleted []*record.DeletedRecord
+
+	assert.NoError(t, service.ScanDeletedRecords(0, func(rec *record.DeletedRecord) bool {
+		deleted = append(deleted, rec)
+		return true
+	}))
+
+	assert.Len(t, deleted, 1)
+	assert.Equal(t, int64(2), deleted[0].Id)
+	assert.Equal(t, "deleted", deleted[0].StrValue)
+	assert.WithinDuration(t, time.Now(), deleted[0].DeletedAt, time.Minute)
+
+	restored, err := service.RestoreRecord(4)
+	assert.NoError(t, err)
+	assert.Nil(t, restored)
+
+	stats, err := service.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Stats{Records: 2, DeletedRecords: 2, CorruptRecords: 1}, *stats)
+
+	backup, err := os.ReadFile(tmpfile.Name() + ".v0.bak")
+	assert.NoError(t, err)
+	assert.Equal(t, 5*98, len(backup))
+}
+
+func TestMigrateFileToTarget(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "v0_source_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	targetPath := tmpfile.Name() + ".target"
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	v0Record := encodeV0Record(&record.Record{Id: 1, IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	tmpfile.Write(v0Record)
+	// incomplete record is discarded
+	tmpfile.Write(v0Record[:50])
+
+	assert.NoError(t, MigrateFile(tmpfile.Name(), targetPath))
+
+	source, err := os.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, 98+50, len(source))
+
+	service := newTestService(t, targetPath)
+
+	rec, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, rec)
+	assert.Equal(t, "foo", rec.StrValue)
+
+	stat, err := os.Stat(targetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+recordSize), stat.Size())
+}
+
+// encodeV0Record function encodes record in format of headerless version 0 file for test
+func encodeV0Record(rec *record.Record) []byte {
+	var buffer bytes.Buffer
+
+	binary.Write(&buffer, binary.LittleEndian, rec.Id)
+	binary.Write(&buffer, binary.LittleEndian, rec.IntValue)
+
+	strBytes := make([]byte, 64)
+	copy(strBytes, rec.StrValue)
+	buffer.Write(strBytes)
+
+	boolByte := byte(0)
+	if rec.BoolValue {
+		boolByte = byte(1)
+	}
+
+	buffer.WriteByte(boolByte)
+
+	timeBytes, _ := rec.TimeValue.MarshalBinary()
+	buffer.Write(append(timeBytes, make([]byte, 16-len(timeBytes))...))
+	buffer.WriteByte('\n')
+
+	return buffer.Bytes()
+}
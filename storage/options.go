@@ -0,0 +1,78 @@
+package storage
+
+import "time"
+
+// Option function that configures optional behaviour of storage service
+type Option func(service *service)
+
+// WithSlotReuse option enables reusing of deleted (tombstoned) record slots for new records
+// When it is disabled every new record is appended to end of file and ids are monotonically increasing
+func WithSlotReuse(enabled bool) Option {
+	return func(service *service) {
+		service.reuseSlots = enabled
+	}
+}
+
+// WithSyncPolicy option sets when written data are flushed to disk
+// Interval is used only by SyncInterval policy
+func WithSyncPolicy(policy SyncPolicy, interval time.Duration) Option {
+	return func(service *service) {
+		service.syncPolicy = policy
+		service.syncInterval = interval
+	}
+}
+
+// WithMaxStrLength option sets maximal length of StrValue in bytes, longer value is rejected
+// history entry with longer StrValue is read as damaged entry
+func WithMaxStrLength(length int) Option {
+	return func(service *service) {
+		service.maxStrLength = length
+	}
+}
+
+// WithAutoMigrate option enables migration of storage file in older format when service is created
+// Original file is kept with version suffix
+func WithAutoMigrate(enabled bool) Option {
+	return func(service *service) {
+		service.autoMigrate = enabled
+	}
+}
+
+// WithIndexes option enables secondary indexes of fields IntValue and TimeValue
+// Indexes are used for lookup of records by range of field values
+func WithIndexes(fields ...string) Option {
+	return func(service *service) {
+		service.indexedFields = fields
+	}
+}
+
+// WithFullTextSearch option enables full-text index of words in StrValue
+func WithFullTextSearch(enabled bool) Option {
+	return func(service *service) {
+		service.fullTextSearch = enabled
+	}
+}
+
+// WithHistory option enables history of records, previous version of record is stored before every change
+// History is used for reading of record at point in time
+func WithHistory(enabled bool) Option {
+	return func(service *service) {
+		service.history = enabled
+	}
+}
+
+// WithIdempotencyTTL option enables idempotency keys of created records, key is kept for given time to live
+// Record created with the same key within time to live is not created again, zero disables idempotency keys
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(service *service) {
+		service.idempotencyTTL = ttl
+	}
+}
+
+// WithDeletedRetention option sets time for which slot of deleted record is not reused by new record
+// Deleted record can be restored until its slot is reused
+func WithDeletedRetention(retention time.Duration) Option {
+	return func(service *service) {
+		service.deletedRetention = retention
+	}
+}
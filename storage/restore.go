@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"encoding/binary"
+	"interviewtest/record"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RestoreRecord method restores deleted record whose data are still stored in its slot
+// id of record is written back into slot, other data of record are not changed
+// nil is returned when slot does not hold deleted record, ErrRecordExists when record is not deleted
+func (service *service) RestoreRecord(id int64) (*record.Record, error) {
+	service.lockWrite()
+	defer service.unlockWrite()
+
+	if id < 1 {
+		return nil, nil
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	slot := buffer[:]
+	recPos := slotPosition(id)
+
+	if _, err := service.readAt(slot, recPos); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if storedID := int64(binary.LittleEndian.Uint64(slot)); storedID == id {
+		return nil, errors.Wrapf(record.ErrRecordExists, "record %d is not deleted", id)
+	} else if storedID != 0 || !restorable(slot) {
+		return nil, nil
+	}
+
+	if !validChecksum(slot) {
+		return nil, errors.Wrapf(record.ErrCorruptRecord, "deleted record %d", id)
+	}
+
+	restored, err := decodeRecord(slot, service.heapFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	restored.Id = id
+
+	if service.historyFile != nil {
+		if err := service.appendHistory(recPos, restored); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	idBytes := make([]byte, payloadOffset)
+	binary.LittleEndian.PutUint64(idBytes, uint64(id))
+
+	if err := service.writeAt(recPos, idBytes); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	service.afterWrite(func() {
+		service.updateIndexes(id, nil, restored)
+		service.updateSearchIndex(id, restored)
+	})
+
+	return restored, nil
+}
+
+// ScanDeletedRecords method iterates deleted records which can be restored with id greater than afterID in order of id
+// empty slots are skipped, corrupted records are skipped with warning
+// iteration stops when callback returns false, callback must not call methods of storage
+func (service *service) ScanDeletedRecords(afterID int64, fn func(deleted *record.DeletedRecord) bool) error {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	if afterID < 0 {
+		afterID = 0
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(service.storageFile, slotPosition(afterID+1))
+	slot := buffer[:]
+
+	for id := afterID + 1; ; id++ {
+		if _, err := io.ReadFull(reader, slot); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if binary.LittleEndian.Uint64(slot) != 0 || !restorable(slot) {
+			continue
+		}
+
+		if !validChecksum(slot) {
+			log.Warnf("Corrupted deleted record %d skipped", id)
+			continue
+		}
+
+		rec, err := decodeRecord(slot, service.heapFile)
+
+		if errors.Is(err, record.ErrCorruptRecord) {
+			log.Warnf("Corrupted deleted record %d skipped: %s", id, err)
+			continue
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		rec.Id = id
+		deletedAt := deletedTime(slot)
+
+		if !fn(&record.DeletedRecord{Record: rec, DeletedAt: deletedAt, ReusableAt: deletedAt.Add(service.deletedRetention)}) {
+			return nil
+		}
+	}
+}
+
+// restorable function reports whether deleted slot holds data of record
+// empty slot written before record created after end of file has zero version
+func restorable(slot []byte) bool {
+	return binary.LittleEndian.Uint64(slot[versionOffset:]) != 0
+}
+
+// deletedTime function returns time of delete stored in slot, zero time for empty slot
+func deletedTime(slot []byte) time.Time {
+	deletedAt := int64(binary.LittleEndian.Uint64(slot[deletedAtOffset:]))
+
+	if deletedAt == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, deletedAt).UTC()
+}
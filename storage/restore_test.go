@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreRecord(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "restore_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"), WithDeletedRetention(time.Hour))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+	longValue := strings.Repeat("x", 100)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: longValue, TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	// slots skipped by record created after end of file are empty
+	assert.NoError(t, service.CreateRecordAt(3, &record.Record{IntValue: 43, StrValue: "foo", TimeValue: &testingTime}))
+
+	deleted, err := service.DeleteRecord(1)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	var deletedRecords []*record.DeletedRecord
+
+	assert.NoError(t, service.ScanDeletedRecords(0, func(rec *record.DeletedRecord) bool {
+		deletedRecords = append(deletedRecords, rec)
+		return true
+	}))
+
+	assert.Len(t, deletedRecords, 1)
+	assert.Equal(t, int64(1), deletedRecords[0].Id)
+	assert.Equal(t, longValue, deletedRecords[0].StrValue)
+	assert.WithinDuration(t, time.Now(), deletedRecords[0].DeletedAt, time.Minute)
+	assert.Equal(t, deletedRecords[0].DeletedAt.Add(time.Hour), deletedRecords[0].ReusableAt)
+
+	restored, err := service.RestoreRecord(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, restored)
+	assert.Equal(t, int64(1), restored.Id)
+	assert.Equal(t, int64(42), restored.IntValue)
+	assert.Equal(t, longValue, restored.StrValue)
+
+	rec, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, restored, rec)
+
+	ids, err := service.LookupRange(intRange(42, 42))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, ids)
+
+	_, err = service.RestoreRecord(1)
+	assert.ErrorIs(t, err, record.ErrRecordExists)
+
+	for _, id := range []int64{2, 99} {
+		restored, err := service.RestoreRecord(id)
+		assert.NoError(t, err)
+		assert.Nil(t, restored)
+	}
+
+	stats, err := service.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Stats{Records: 2, DeletedRecords: 1}, *stats)
+}
+
+func TestDeletedRetention(t *testing.T) {
+	tests := []struct {
+		name       string
+		retention  time.Duration
+		expectedID int64
+	}{{
+		name:       "Slot of deleted record is reused without retention",
+		expectedID: 1,
+	}, {
+		name:       "Slot of deleted record is not reused within retention",
+		retention:  time.Hour,
+		expectedID: 3,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpfile, err := os.CreateTemp("", "retention_records.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			testtools.CleanupFiles(t, tmpfile.Name())
+			tmpfile.Close()
+
+			testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+			service, err := NewService(tmpfile.Name(), WithSlotReuse(true), WithDeletedRetention(tt.retention))
+			assert.NoError(t, err)
+
+			for i := 1; i <= 2; i++ {
+				_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+				assert.NoError(t, err)
+			}
+
+			_, err = service.DeleteRecord(1)
+			assert.NoError(t, err)
+
+			service.Close()
+
+			// free slots are loaded with time of delete
+			reopened := newTestService(t, tmpfile.Name(), WithSlotReuse(true), WithDeletedRetention(tt.retention))
+
+			createdID, err := reopened.CreateRecord(&record.Record{IntValue: 3, StrValue: "foo", TimeValue: &testingTime})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedID, createdID)
+
+			restored, err := reopened.RestoreRecord(1)
+
+			if tt.retention == 0 {
+				// data of deleted record are overwritten by new record
+				assert.ErrorIs(t, err, record.ErrRecordExists)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), restored.IntValue)
+		})
+	}
+}
+
+func TestRestoreCompactedRecord(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "restore_compacted_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithDeletedRetention(time.Hour))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+	longStrValue := strings.Repeat("foo", 50)
+
+	for i := 1; i <= 3; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: longStrValue, TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	for _, id := range []int64{1, 2} {
+		_, err := service.DeleteRecord(id)
+		assert.NoError(t, err)
+	}
+
+	// deleted record without data is dropped, deleted record within retention is moved
+	assert.NoError(t, service.writeAt(slotPosition(1)+versionOffset, make([]byte, 8)))
+
+	idMapping, err := service.Compact()
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int64{2: 1, 3: 2}, idMapping)
+
+	restored, err := service.RestoreRecord(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, restored)
+	assert.Equal(t, int64(1), restored.Id)
+	assert.Equal(t, int64(2), restored.IntValue)
+	assert.Equal(t, longStrValue, restored.StrValue)
+
+	rec, err := service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), rec.IntValue)
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/binary"
+	"interviewtest/record"
+	"io"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ScanRecords method iterates records with id greater than afterID in order of id
+// deleted records are skipped, corrupted records are skipped with warning
+// iteration stops when callback returns false, callback must not call methods of storage
+func (service *service) ScanRecords(afterID int64, fn func(rec *record.Record) bool) error {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	if afterID < 0 {
+		afterID = 0
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(service.storageFile, slotPosition(afterID+1))
+	slot := buffer[:]
+
+	for {
+		if _, err := io.ReadFull(reader, slot); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		id := binary.LittleEndian.Uint64(slot)
+
+		if id == 0 {
+			continue
+		}
+
+		if !validChecksum(slot) {
+			log.Warnf("Corrupted record %d skipped", id)
+			continue
+		}
+
+		rec, err := decodeRecord(slot, service.heapFile)
+
+		if errors.Is(err, record.ErrCorruptRecord) {
+			log.Warnf("Corrupted record %d skipped: %s", id, err)
+			continue
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if !fn(rec) {
+			return nil
+		}
+	}
+}
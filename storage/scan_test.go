@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanRecords(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "scan_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 6; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err = service.DeleteRecord(2)
+	assert.NoError(t, err)
+
+	// flip bit in StrValue of fourth record
+	_, err = tmpfile.WriteAt([]byte{'g'}, slotPosition(4)+strValueOffset)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		afterID     int64
+		limit       int
+		expectedIDs []int64
+	}{{
+		name:        "Scan all records",
+		afterID:     0,
+		limit:       10,
+		expectedIDs: []int64{1, 3, 5, 6},
+	}, {
+		name:        "Scan records after id 3",
+		afterID:     3,
+		limit:       10,
+		expectedIDs: []int64{5, 6},
+	}, {
+		name:        "Scan is stopped by callback",
+		afterID:     0,
+		limit:       2,
+		expectedIDs: []int64{1, 3},
+	}, {
+		name:        "Scan after last record",
+		afterID:     6,
+		limit:       10,
+		expectedIDs: nil,
+	}, {
+		name:        "Scan after end of file",
+		afterID:     99,
+		limit:       10,
+		expectedIDs: nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int64
+
+			err := service.ScanRecords(tt.afterID, func(rec *record.Record) bool {
+				assert.Equal(t, rec.Id, rec.IntValue)
+				ids = append(ids, rec.Id)
+				return len(ids) < tt.limit
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
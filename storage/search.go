@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"encoding/binary"
+	"interviewtest/record"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Full-text index of StrValue is inverted index of lowercase words kept in memory
+// words of every record are kept for update of index, index file contains words of records only
+// payload of index file is count(8) | records, record is id(8) | word count(4) | words,
+// word is length(4) | word | frequency(4)
+const searchIndexField = "StrValue"
+
+var searchIndexMagic = []byte("IVTFTS\x00\x01")
+
+// searchIndex structure with inverted index of words and words of every record
+type searchIndex struct {
+	postings map[string]map[int64]uint32
+	// words sorted for prefix queries
+	words   []string
+	records map[int64]map[string]uint32
+}
+
+// searchTerm structure with one word of query, prefix term matches all words starting with the word
+type searchTerm struct {
+	word   string
+	prefix bool
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[int64]uint32),
+		records:  make(map[int64]map[string]uint32),
+	}
+}
+
+// tokenize function splits value into lowercase words of letters and digits
+func tokenize(value string) []string {
+	return strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// parseSearchQuery function splits query into terms, word ending with '*' is prefix term
+func parseSearchQuery(query string) []searchTerm {
+	var terms []searchTerm
+
+	for _, field := range strings.Fields(query) {
+		words := tokenize(field)
+
+		for i, word := range words {
+			terms = append(terms, searchTerm{word: word, prefix: i == len(words)-1 && strings.HasSuffix(field, "*")})
+		}
+	}
+
+	return terms
+}
+
+// SearchRecords method returns ids of records with StrValue containing all words of query ordered by relevance
+// word of query ending with '*' matches all words with the prefix, ErrNotIndexed is returned when search is disabled
+func (service *service) SearchRecords(query string, limit int) ([]record.SearchHit, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	if service.searchIndex == nil {
+		return nil, errors.Wrapf(record.ErrNotIndexed, "field %s", searchIndexField)
+	}
+
+	return service.searchIndex.search(parseSearchQuery(query), limit), nil
+}
+
+// openSearchIndex method loads full-text index, missing or stale index is rebuilt
+func (service *service) openSearchIndex() error {
+	if !service.fullTextSearch {
+		return nil
+	}
+
+	stat, err := service.storageFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	path := indexFilePath(service.storageFilePath, searchIndexField)
+
+	searchIndex := newSearchIndex()
+
+	if err := searchIndex.load(path, stat); err != nil {
+		log.Infof("Full-text index is rebuilt: %s", err)
+
+		if searchIndex, err = buildSearchIndex(service.storageFile, service.heapFile); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// index in memory is changed by every write, file is written again on close
+	os.Remove(path)
+
+	service.searchIndex = searchIndex
+	return nil
+}
+
+// buildSearchIndex function builds full-text index by scan of all records in storage file
+func buildSearchIndex(storageFile, heapFile *os.File) (*searchIndex, error) {
+	searchIndex := newSearchIndex()
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(storageFile, headerSize)
+	slot := buffer[:]
+
+	// only StrValue of record is indexed, one record is reused for all slots
+	rec := &record.Record{}
+
+	for pos := int64(headerSize); ; pos += recordSize {
+		if _, err := io.ReadFull(reader, slot); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if binary.LittleEndian.Uint64(slot) == 0 || !validChecksum(slot) {
+			continue
+		}
+
+		err := decodeRecordInto(slot, heapFile, rec)
+
+		if errors.Is(err, record.ErrCorruptRecord) {
+			continue
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		searchIndex.add(slotID(pos), rec.StrValue)
+	}
+
+	return searchIndex, nil
+}
+
+// updateSearchIndex method replaces words of record in full-text index, nil record is removed from index
+func (service *service) updateSearchIndex(id int64, current *record.Record) {
+	if service.searchIndex == nil {
+		return
+	}
+
+	service.searchIndex.remove(id)
+
+	if current != nil {
+		service.searchIndex.add(id, current.StrValue)
+	}
+}
+
+func (searchIndex *searchIndex) add(id int64, value string) {
+	frequencies := make(map[string]uint32)
+
+	for _, word := range tokenize(value) {
+		frequencies[word]++
+	}
+
+	if len(frequencies) == 0 {
+		return
+	}
+
+	searchIndex.addWords(id, frequencies)
+}
+
+func (searchIndex *searchIndex) addWords(id int64, frequencies map[string]uint32) {
+	searchIndex.records[id] = frequencies
+
+	for word, frequency := range frequencies {
+		posting, ok := searchIndex.postings[word]
+
+		if !ok {
+			posting = make(map[int64]uint32)
+			searchIndex.postings[word] = posting
+
+			i := sort.SearchStrings(searchIndex.words, word)
+			searchIndex.words = append(searchIndex.words, "")
+			copy(searchIndex.words[i+1:], searchIndex.words[i:])
+			searchIndex.words[i] = word
+		}
+
+		posting[id] = frequency
+	}
+}
+
+func (searchIndex *searchIndex) remove(id int64) {
+	for word := range searchIndex.records[id] {
+		posting := searchIndex.postings[word]
+		delete(posting, id)
+
+		if len(posting) == 0 {
+			delete(searchIndex.postings, word)
+
+			i := sort.SearchStrings(searchIndex.words, word)
+			searchIndex.words = append(searchIndex.words[:i], searchIndex.words[i+1:]...)
+		}
+	}
+
+	delete(searchIndex.records, id)
+}
+
+// search method returns records matching all terms ordered by relevance
+// relevance is sum of logarithmic word frequency multiplied by inverse document frequency of every term
+// divided by square root of number of words in record, so shorter record with the same words is more relevant
+func (searchIndex *searchIndex) search(terms []searchTerm, limit int) []record.SearchHit {
+	hits := make([]record.SearchHit, 0)
+
+	if len(terms) == 0 {
+		return hits
+	}
+
+	scores := make(map[int64]float64)
+
+	for i, term := range terms {
+		frequencies := searchIndex.termFrequencies(term)
+		idf := math.Log(1 + float64(len(searchIndex.records))/float64(len(frequencies)+1))
+
+		termScores := make(map[int64]float64)
+
+		for id, frequency := range frequencies {
+			// every term must match, records without previous terms are skipped
+			if _, ok := scores[id]; i > 0 && !ok {
+				continue
+			}
+
+			termScores[id] = scores[id] + (1+math.Log(float64(frequency)))*idf
+		}
+
+		scores = termScores
+	}
+
+	for id, score := range scores {
+		var wordCount uint32
+
+		for _, frequency := range searchIndex.records[id] {
+			wordCount += frequency
+		}
+
+		hits = append(hits, record.SearchHit{ID: id, Score: score / math.Sqrt(float64(wordCount))})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+
+		return hits[i].ID < hits[j].ID
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
+
+// termFrequencies method returns frequency of term in every record containing the term
+func (searchIndex *searchIndex) termFrequencies(term searchTerm) map[int64]uint32 {
+	if !term.prefix {
+		return searchIndex.postings[term.word]
+	}
+
+	frequencies := make(map[int64]uint32)
+
+	for i := sort.SearchStrings(searchIndex.words, term.word); i < len(searchIndex.words); i++ {
+		if !strings.HasPrefix(searchIndex.words[i], term.word) {
+			break
+		}
+
+		for id, frequency := range searchIndex.postings[searchIndex.words[i]] {
+			frequencies[id] += frequency
+		}
+	}
+
+	return frequencies
+}
+
+// save method writes words of all records into index file
+func (searchIndex *searchIndex) save(path string, stat os.FileInfo) error {
+	payload := binary.LittleEndian.AppendUint64(nil, uint64(len(searchIndex.records)))
+
+	for id, frequencies := range searchIndex.records {
+		payload = binary.LittleEndian.AppendUint64(payload, uint64(id))
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(len(frequencies)))
+
+		for word, frequency := range frequencies {
+			payload = binary.LittleEndian.AppendUint32(payload, uint32(len(word)))
+			payload = append(payload, word...)
+			payload = binary.LittleEndian.AppendUint32(payload, frequency)
+		}
+	}
+
+	return writeIndexFile(path, searchIndexMagic, stat, payload)
+}
+
+// load method reads words of all records from index file and builds inverted index
+func (searchIndex *searchIndex) load(path string, stat os.FileInfo) error {
+	payload, err := readIndexFile(path, searchIndexMagic, stat)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	invalidSize := errors.New("invalid size of index file")
+
+	if len(payload) < 8 {
+		return invalidSize
+	}
+
+	count := binary.LittleEndian.Uint64(payload)
+	payload = payload[8:]
+
+	for ; count > 0; count-- {
+		if len(payload) < 12 {
+			return invalidSize
+		}
+
+		id := int64(binary.LittleEndian.Uint64(payload))
+		frequencies := make(map[string]uint32)
+
+		wordCount := binary.LittleEndian.Uint32(payload[8:])
+		payload = payload[12:]
+
+		for ; wordCount > 0; wordCount-- {
+			if len(payload) < 4 || uint64(len(payload)) < 8+uint64(binary.LittleEndian.Uint32(payload)) {
+				return invalidSize
+			}
+
+			length := binary.LittleEndian.Uint32(payload)
+			frequencies[string(payload[4:4+length])] = binary.LittleEndian.Uint32(payload[4+length:])
+			payload = payload[8+length:]
+		}
+
+		searchIndex.addWords(id, frequencies)
+	}
+
+	if len(payload) != 0 {
+		return invalidSize
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchIDs(t *testing.T, service *service, query string) []int64 {
+	hits, err := service.SearchRecords(query, 10)
+	assert.NoError(t, err)
+
+	ids := make([]int64, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	return ids
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"hello", "world", "42", "žluťoučký", "kůň"}, tokenize("Hello, WORLD-42! Žluťoučký kůň"))
+	assert.Empty(t, tokenize(" ,.- "))
+}
+
+func TestSearchRecords(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "search_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithFullTextSearch(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for _, strValue := range []string{
+		"quick brown fox",
+		"lazy dog sleeps",
+		"quick dog, quick fox",
+		"Foxtrot dance",
+		"brown dog",
+	} {
+		_, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: strValue, TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		expectedIDs []int64
+	}{{
+		name:        "Shorter record is more relevant",
+		query:       "dog",
+		expectedIDs: []int64{5, 2, 3},
+	}, {
+		name:        "Record with more occurrences of word is more relevant",
+		query:       "quick",
+		expectedIDs: []int64{3, 1},
+	}, {
+		name:        "All words must match",
+		query:       "Brown DOG",
+		expectedIDs: []int64{5},
+	}, {
+		name:        "Prefix query",
+		query:       "fox*",
+		expectedIDs: []int64{4, 1, 3},
+	}, {
+		name:        "Word is not prefix without asterisk",
+		query:       "fox",
+		expectedIDs: []int64{1, 3},
+	}, {
+		name:        "Unknown word",
+		query:       "cat",
+		expectedIDs: []int64{},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedIDs, searchIDs(t, service, tt.query))
+		})
+	}
+
+	_, err = service.EditRecord(2, &record.Record{Id: 2, IntValue: 42, StrValue: "lazy cat", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.DeleteRecord(5)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int64{3}, searchIDs(t, service, "dog"))
+	assert.Equal(t, []int64{2}, searchIDs(t, service, "cat"))
+
+	hits, err := service.SearchRecords("dog*", 1)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
+
+func TestSearchIndexPersistedAndRebuilt(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "persisted_search_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithFullTextSearch(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	longValue := "words stored in heap file, the value is longer than sixty four bytes and contains needle"
+
+	for _, strValue := range []string{"first needle", "second", longValue} {
+		_, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: strValue, TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	service.Close()
+
+	service = newTestService(t, tmpfile.Name(), WithFullTextSearch(true))
+	assert.Equal(t, []int64{1, 3}, searchIDs(t, service, "needle"))
+
+	_, err = service.DeleteRecord(1)
+	assert.NoError(t, err)
+
+	_, err = service.Compact()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int64{2}, searchIDs(t, service, "needle"))
+
+	service.Close()
+
+	// storage file is changed by service without full-text index, index file is stale
+	service = newTestService(t, tmpfile.Name())
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: "third needle", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.SearchRecords("needle", 10)
+	assert.ErrorIs(t, err, record.ErrNotIndexed)
+
+	service.Close()
+
+	service = newTestService(t, tmpfile.Name(), WithFullTextSearch(true))
+	assert.ElementsMatch(t, []int64{2, 3}, searchIDs(t, service, "needle"))
+}
@@ -6,6 +6,7 @@ import (
 	"interviewtest/record"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,236 +14,726 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const recordSize = 98
+// Record is stored in slot of fixed size after file header, ID of record is defined by position of slot
+// id(8) | deletedAt(8) | version(8) | IntValue(8) | StrLength(4) | StrValue(64) | BoolValue(1) | TimeValue(16) | crc32c(4) | '\n'(1)
+// StrValue longer than 64 bytes is stored in heap file, slot holds position of heap entry
+// version is incremented by every write into slot, it is kept when record is deleted
+// deletedAt is unix nanoseconds of delete of record, data of deleted record are kept for restore
+const (
+	recordSize = 122
+	// checksum covers all fields except id and deletedAt, so deleted record keeps valid checksum
+	deletedAtOffset = 8
+	payloadOffset   = 16
+	versionOffset   = 16
+	intValueOffset  = 24
+	strLengthOffset = 32
+	strValueOffset  = 36
+	inlineStrLength = 64
+	boolValueOffset = 100
+	timeValueOffset = 101
+	checksumOffset  = 117
+	// record can be created at most this number of slots after end of file, skipped slots are empty
+	maxSlotGap = 1024
+)
 
 // Service interface that provides method for working with binary file storage
 type Service interface {
 	GetRecord(id int64) (*record.Record, error)
+	ScanRecords(afterID int64, fn func(rec *record.Record) bool) error
+	LookupRange(valueRange record.Range) ([]int64, error)
+	LookupRangeAfter(valueRange record.Range, afterID int64, limit int) ([]int64, error)
+	LookupOrdered(valueRange record.Range, after *record.IndexEntry, descending bool, limit int) ([]record.IndexEntry, error)
+	SearchRecords(query string, limit int) ([]record.SearchHit, error)
+	RecordHistory(id int64) ([]record.Revision, error)
+	GetRecordAsOf(id int64, asOf time.Time) (*record.Record, error)
 	CreateRecord(rec *record.Record) (int64, error)
+	CreateRecordAt(id int64, rec *record.Record) error
+	CreateRecordIdempotent(key string, rec *record.Record) (bool, error)
 	EditRecord(id int64, rec *record.Record) (int64, error)
+	UpdateRecord(id int64, update func(rec *record.Record) error) (*record.Record, error)
 	DeleteRecord(id int64) (bool, error)
+	DeleteRecordIf(id int64, condition func(version int64) error) (bool, error)
+	RestoreRecord(id int64) (*record.Record, error)
+	ApplyBatch(operations []record.Operation) error
+	Begin() (record.Transaction, error)
+	ScanDeletedRecords(afterID int64, fn func(deleted *record.DeletedRecord) bool) error
+	Compact() (map[int64]int64, error)
+	Stats() (*record.Stats, error)
+	CheckIntegrity() ([]record.IntegrityIssue, error)
+	Snapshot(dir string) (*record.Snapshot, error)
 	Close()
 }
 
 type service struct {
-	storageFilePath string
-	storageFile     *os.File
-	mu              sync.Mutex
+	storageFilePath    string
+	storageFile        *os.File
+	mu                 sync.RWMutex
+	writeMu            sync.Mutex
+	reuseSlots         bool
+	freeSlots          []int64
+	walFile            *os.File
+	syncPolicy         SyncPolicy
+	syncInterval       time.Duration
+	unsynced           bool
+	stopSync           chan struct{}
+	syncStopped        chan struct{}
+	closed             bool
+	autoMigrate        bool
+	heapFile           *os.File
+	heapSize           int64
+	heapGeneration     uint32
+	maxStrLength       int
+	indexedFields      []string
+	indexes            map[string]*index
+	fullTextSearch     bool
+	searchIndex        *searchIndex
+	history            bool
+	historyFile        *os.File
+	historySize        int64
+	historyEntries     map[int64][]historyRef
+	deletedRetention   time.Duration
+	tx                 *transaction
+	idempotencyTTL     time.Duration
+	idempotencyFile    *os.File
+	idempotencyKeys    map[string]idempotencyKey
+	idempotencyAppends int
 }
 
 // NewService constructor for create new binary file storage
-// constructor create binary file
-func NewService(fileStoragePath string) (Service, error) {
+// constructor create binary file, optional behaviour is configured by options
+func NewService(fileStoragePath string, options ...Option) (Service, error) {
+	if err := recoverCompaction(fileStoragePath); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
 	file, err := os.OpenFile(fileStoragePath, os.O_CREATE|os.O_RDWR, os.ModePerm)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	return &service{
+	service := &service{
 		storageFilePath: fileStoragePath,
 		storageFile:     file,
-	}, nil
-}
-
-// GetRecord method for get record by id from binary file
-func (service *service) GetRecord(id int64) (*record.Record, error) {
-	service.mu.Lock()
-	defer service.mu.Unlock()
+		syncPolicy:      SyncAlways,
+		syncInterval:    time.Second,
+		maxStrLength:    defaultMaxStrLength,
+	}
 
-	recPos := (id - 1) * recordSize
+	for _, option := range options {
+		option(service)
+	}
 
-	_, err := service.storageFile.Seek(recPos, io.SeekStart)
-	if err != nil {
+	if err := service.openWal(); err != nil {
+		file.Close()
 		return nil, errors.WithStack(err)
 	}
 
-	var rec record.Record
-
-	if err := binary.Read(service.storageFile, binary.LittleEndian, &rec.Id); err == io.EOF {
-		return nil, nil
-	} else if err != nil {
+	if err := service.openHeader(); err != nil {
+		service.Close()
 		return nil, errors.WithStack(err)
 	}
 
-	if err := binary.Read(service.storageFile, binary.LittleEndian, &rec.IntValue); err != nil {
+	if err := service.truncateTornRecord(); err != nil {
+		service.Close()
 		return nil, errors.WithStack(err)
 	}
 
-	strBytes := make([]byte, 64)
-	if _, err := service.storageFile.Read(strBytes); err != nil {
+	if err := service.openIndexes(); err != nil {
+		service.Close()
 		return nil, errors.WithStack(err)
 	}
 
-	rec.StrValue = string(bytes.TrimRight(strBytes, string(rune(0))))
+	if service.history {
+		if err := service.openHistory(); err != nil {
+			service.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
 
-	boolByte := make([]byte, 1)
-	if _, err := service.storageFile.Read(boolByte); err != nil {
-		return nil, errors.WithStack(err)
+	if service.idempotencyTTL > 0 {
+		if err := service.openIdempotencyKeys(); err != nil {
+			service.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if service.reuseSlots {
+		if err := service.loadFreeSlots(); err != nil {
+			service.Close()
+			return nil, errors.WithStack(err)
+		}
 	}
 
-	rec.BoolValue = boolByte[0] != 0
+	service.startPeriodicSync()
 
-	timeBytes := make([]byte, 16)
-	if _, err := service.storageFile.Read(timeBytes); err != nil {
-		return nil, errors.WithStack(err)
+	return service, nil
+}
+
+// GetRecord method for get record by id from binary file
+// checksum of record is verified, ErrCorruptRecord is returned for damaged record
+// slot is read by positional read, so records are read concurrently under shared lock
+func (service *service) GetRecord(id int64) (*record.Record, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	return service.getRecord(id)
+}
+
+// getRecord method reads record by id, caller holds the lock
+func (service *service) getRecord(id int64) (*record.Record, error) {
+	return service.readRecord(service.storageFile.ReadAt, id)
+}
+
+// readRecord method reads record by id by given read function
+func (service *service) readRecord(readAt func(data []byte, pos int64) (int, error), id int64) (*record.Record, error) {
+	if id < 1 {
+		return nil, nil
 	}
 
-	timeBytes = bytes.Trim(timeBytes, "\x00")
+	buffer := newSlot()
+	defer buffer.release()
 
-	var t time.Time
+	slot := buffer[:]
 
-	if err := t.UnmarshalBinary(timeBytes); err != nil {
+	// storage file contains complete slots only, slot after end of file does not exist
+	if _, err := readAt(slot, slotPosition(id)); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	rec.TimeValue = &t
+	// deleted record is not returned
+	if binary.LittleEndian.Uint64(slot) == 0 {
+		return nil, nil
+	}
 
-	if _, err := service.storageFile.Seek(1, io.SeekCurrent); err != nil {
-		return nil, errors.WithStack(err)
+	if !validChecksum(slot) {
+		return nil, errors.Wrapf(record.ErrCorruptRecord, "record %d", id)
 	}
 
-	return &rec, nil
+	return decodeRecord(slot, service.heapFile)
 }
 
 // CreateRecord method for create record in binary file
 // ID of record is the defined position of the record in the file
-// Every new file is appended to end of file
+// Every new file is appended to end of file, or stored into deleted slot when slot reuse is enabled
 func (service *service) CreateRecord(rec *record.Record) (int64, error) {
-	service.mu.Lock()
-	defer service.mu.Unlock()
+	service.lockWrite()
+	defer service.unlockWrite()
 
-	pos, err := service.storageFile.Seek(0, io.SeekEnd)
+	return service.createRecord(rec)
+}
+
+// createRecord method creates record in next free slot, caller holds the lock
+func (service *service) createRecord(rec *record.Record) (int64, error) {
+	// free slot is not taken by invalid record
+	if err := service.checkRecord(rec); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	pos, err := service.nextFreeSlot()
 
 	if err != nil {
 		return 0, errors.WithStack(err)
 	}
 
-	rec.Id = pos/recordSize + 1
+	rec.Id = slotID(pos)
 
-	if err := service.writeRecord(rec, service.storageFile); err != nil {
+	if err := service.writeRecord(pos, rec); err != nil {
 		return 0, errors.WithStack(err)
 	}
 
 	return rec.Id, nil
 }
 
+// CreateRecordAt method creates record with given id in deleted slot or in slot after end of file
+// slots between end of file and new record are written as deleted slots
+// ErrRecordExists is returned when slot contains record, ErrIDOutOfRange for id far after end of file
+func (service *service) CreateRecordAt(id int64, rec *record.Record) error {
+	service.lockWrite()
+	defer service.unlockWrite()
+
+	return service.createRecordAt(id, rec)
+}
+
+// createRecordAt method creates record with given id, caller holds the lock
+func (service *service) createRecordAt(id int64, rec *record.Record) error {
+	if id < 1 {
+		return errors.Wrapf(record.ErrIDOutOfRange, "id %d", id)
+	}
+
+	if err := service.checkRecord(rec); err != nil {
+		return errors.WithStack(err)
+	}
+
+	size, err := service.storageSize()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	recPos := slotPosition(id)
+
+	if recPos < size {
+		storedID, err := service.readStoredID(recPos)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if storedID != 0 {
+			return errors.Wrapf(record.ErrRecordExists, "id %d", id)
+		}
+	} else if err := service.writeEmptySlots(size, recPos); err != nil {
+		return errors.WithStack(err)
+	}
+
+	rec.Id = id
+
+	return service.writeRecord(recPos, rec)
+}
+
 // EditRecord method for edit record in binary file by id and return updated id
+// zero id is returned when record does not exist, id of record is preserved
 func (service *service) EditRecord(id int64, updatedRecord *record.Record) (int64, error) {
-	service.mu.Lock()
-	defer service.mu.Unlock()
+	service.lockWrite()
+	defer service.unlockWrite()
 
-	recPos := (id - 1) * recordSize
+	return service.editRecord(id, updatedRecord)
+}
+
+// editRecord method replaces existing record, caller holds the lock
+func (service *service) editRecord(id int64, updatedRecord *record.Record) (int64, error) {
+	if id < 1 {
+		return 0, nil
+	}
 
-	if _, err := service.storageFile.Seek(recPos, io.SeekStart); err != nil {
+	recPos := slotPosition(id)
+
+	// deleted slot, slot after end of file and slot with other record are not edited
+	if storedID, err := service.readStoredID(recPos); err != nil {
 		return 0, errors.WithStack(err)
+	} else if storedID != id {
+		return 0, nil
 	}
 
-	if err := service.writeRecord(updatedRecord, service.storageFile); err != nil {
+	updatedRecord.Id = id
+
+	if err := service.writeRecord(recPos, updatedRecord); err != nil {
 		return 0, errors.WithStack(err)
 	}
 
-	return updatedRecord.Id, nil
+	return id, nil
+}
+
+// UpdateRecord method reads record, changes it by update function and writes it under one lock
+// record is not written when update function returns error, nil is returned when record does not exist
+// update function must not call methods of storage
+func (service *service) UpdateRecord(id int64, update func(rec *record.Record) error) (*record.Record, error) {
+	service.lockWrite()
+	defer service.unlockWrite()
+
+	return service.updateRecord(id, update)
+}
+
+// updateRecord method reads, changes and writes record, caller holds the lock
+func (service *service) updateRecord(id int64, update func(rec *record.Record) error) (*record.Record, error) {
+	if id < 1 {
+		return nil, nil
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	slot := buffer[:]
+	recPos := slotPosition(id)
+
+	if _, err := service.readAt(slot, recPos); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if int64(binary.LittleEndian.Uint64(slot)) != id {
+		return nil, nil
+	}
+
+	if !validChecksum(slot) {
+		return nil, errors.Wrapf(record.ErrCorruptRecord, "record %d", id)
+	}
+
+	rec, err := decodeRecord(slot, service.heapFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := update(rec); err != nil {
+		return nil, err
+	}
+
+	rec.Id = id
+
+	if err := service.writeRecord(recPos, rec); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return rec, nil
 }
 
-// DeleteRecord method delete record by id (set id to zero)
-// other data of record other data are not changed
+// DeleteRecord method delete record by id (set id to zero and time of delete)
+// other data of record are not changed, so deleted record can be restored
+// slot is located from id directly, stored id is verified before slot is deleted
 func (service *service) DeleteRecord(id int64) (bool, error) {
+	return service.DeleteRecordIf(id, nil)
+}
+
+// DeleteRecordIf method deletes record only when condition on version of record succeeds
+// record is not deleted when condition returns error, condition is checked under the same lock as delete
+// nil condition deletes record unconditionally
+func (service *service) DeleteRecordIf(id int64, condition func(version int64) error) (bool, error) {
+	service.lockWrite()
+	defer service.unlockWrite()
+
+	return service.deleteRecordIf(id, condition)
+}
+
+// deleteRecordIf method deletes record when condition succeeds, caller holds the lock
+func (service *service) deleteRecordIf(id int64, condition func(version int64) error) (bool, error) {
+	if id < 1 {
+		return false, nil
+	}
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	slot := buffer[:]
+	recPos := slotPosition(id)
+
+	if _, err := service.readAt(slot, recPos); err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	// deleted slot has zero id, slot with other id is reported by integrity check
+	if storedID := int64(binary.LittleEndian.Uint64(slot)); storedID != id {
+		if storedID != 0 {
+			log.Warnf("Slot of record %d contains record %d", id, storedID)
+		}
+
+		return false, nil
+	}
+
+	if condition != nil {
+		if err := condition(int64(binary.LittleEndian.Uint64(slot[versionOffset:]))); err != nil {
+			return false, err
+		}
+	}
+
+	if service.historyFile != nil {
+		if err := service.appendHistorySlot(id, slot); err != nil {
+			return false, errors.WithStack(err)
+		}
+	}
+
+	deleted := indexedRecord(slot)
+
+	tombstone := make([]byte, payloadOffset)
+	binary.LittleEndian.PutUint64(tombstone[deletedAtOffset:], uint64(time.Now().UnixNano()))
+
+	if err := service.writeAt(recPos, tombstone); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	service.afterWrite(func() {
+		service.updateIndexes(id, deleted, nil)
+		service.updateSearchIndex(id, nil)
+	})
+
+	if service.reuseSlots {
+		service.freeSlots = append(service.freeSlots, id)
+	}
+
+	return true, nil
+}
+
+// Close method flushes and close storage file
+func (service *service) Close() {
+	service.stopPeriodicSync()
+
 	service.mu.Lock()
 	defer service.mu.Unlock()
 
-	_, err := service.storageFile.Seek(0, io.SeekStart)
-	if err != nil {
-		return false, errors.WithStack(err)
+	if service.closed {
+		return
+	}
+
+	// open transaction is discarded, its owner gets ErrTransactionDone
+	if service.tx != nil {
+		log.Warn("Open transaction rolled back by close of storage")
+		service.tx.done = true
+		service.rollback()
+		service.writeMu.Unlock()
 	}
 
-	var deletedRecord bool
+	service.closeWal()
 
-	for {
-		var actualId int64
+	if err := service.saveIndexes(); err != nil {
+		log.Errorf("Saving of indexes failed: %s", err)
+	}
+
+	service.closeFiles()
+}
+
+// closeFiles method closes files of storage, service can not be used after its files are closed
+func (service *service) closeFiles() {
+	service.storageFile.Close()
+
+	if service.heapFile != nil {
+		service.heapFile.Close()
+	}
 
-		if err := binary.Read(service.storageFile, binary.LittleEndian, &actualId); err == io.EOF {
+	if service.historyFile != nil {
+		service.historyFile.Close()
+	}
+
+	if service.idempotencyFile != nil {
+		service.idempotencyFile.Close()
+	}
+
+	service.closed = true
+}
+
+// lockWrite method locks service for write, write waits until open transaction is finished
+func (service *service) lockWrite() {
+	service.writeMu.Lock()
+	service.mu.Lock()
+}
+
+// unlockWrite method unlocks service locked for write
+func (service *service) unlockWrite() {
+	service.mu.Unlock()
+	service.writeMu.Unlock()
+}
+
+// loadFreeSlots method scans whole file and collects ids of deleted records ordered by time of delete
+func (service *service) loadFreeSlots() error {
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(service.storageFile, headerSize)
+	slot := buffer[:]
+
+	service.freeSlots = nil
+	deletedAt := make(map[int64]uint64)
+
+	for id := int64(1); ; id++ {
+		if _, err := io.ReadFull(reader, slot); err == io.EOF {
 			break
 		} else if err != nil {
-			return false, errors.WithStack(err)
+			return errors.WithStack(err)
 		}
 
-		if actualId == id {
-			// offset -8 for rewrite id of record
-			_, err = service.storageFile.Seek(-8, io.SeekCurrent)
+		if binary.LittleEndian.Uint64(slot) == 0 {
+			service.freeSlots = append(service.freeSlots, id)
+			deletedAt[id] = binary.LittleEndian.Uint64(slot[deletedAtOffset:])
+		}
+	}
 
-			if err != nil {
-				return false, errors.WithStack(err)
-			}
+	sort.SliceStable(service.freeSlots, func(i, j int) bool {
+		return deletedAt[service.freeSlots[i]] < deletedAt[service.freeSlots[j]]
+	})
 
-			if err := binary.Write(service.storageFile, binary.LittleEndian, int64(0)); err != nil {
-				return false, errors.WithStack(err)
-			}
+	log.Debugf("Found %d free slots", len(service.freeSlots))
+	return nil
+}
+
+// nextFreeSlot method returns position for new record
+// the oldest deleted slot is used when slot reuse is enabled, otherwise end of file
+// slot of record deleted within retention window is not reused, free slots are ordered by time of delete
+func (service *service) nextFreeSlot() (int64, error) {
+	for service.reuseSlots && len(service.freeSlots) > 0 {
+		recPos := slotPosition(service.freeSlots[0])
+
+		storedID, deletedAt, err := service.readTombstone(recPos)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
 
-			deletedRecord = true
-		} else {
-			// offset 90 - skip to next record in file
-			_, err = service.storageFile.Seek(90, io.SeekCurrent)
+		// slot could be taken by record created at its id or by restored record after delete
+		if storedID != 0 {
+			service.freeSlots = service.freeSlots[1:]
+			continue
+		}
 
-			if err != nil {
-				return false, errors.WithStack(err)
-			}
+		if time.Since(deletedAt) < service.deletedRetention {
+			break
 		}
+
+		service.freeSlots = service.freeSlots[1:]
+
+		return recPos, nil
 	}
 
-	return deletedRecord, nil
+	return service.storageSize()
 }
 
-// Close method close storage file
-func (service *service) Close() {
-	service.storageFile.Close()
+// readStoredID method reads id stored in slot at position, zero is returned for slot after end of file
+func (service *service) readStoredID(pos int64) (int64, error) {
+	buffer := newSlot()
+	defer buffer.release()
+
+	storedID := buffer[:8]
+
+	if _, err := service.readAt(storedID, pos); err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return int64(binary.LittleEndian.Uint64(storedID)), nil
 }
 
-func (service *service) writeRecord(rec *record.Record, file *os.File) error {
-	if err := binary.Write(file, binary.LittleEndian, rec.Id); err != nil {
-		return errors.WithStack(err)
+// readTombstone method reads id and time of delete stored in slot at position
+func (service *service) readTombstone(pos int64) (int64, time.Time, error) {
+	buffer := newSlot()
+	defer buffer.release()
+
+	tombstone := buffer[:payloadOffset]
+
+	if _, err := service.readAt(tombstone, pos); err != nil {
+		return 0, time.Time{}, errors.WithStack(err)
+	}
+
+	return int64(binary.LittleEndian.Uint64(tombstone)), deletedTime(tombstone), nil
+}
+
+// readVersion method reads version of record stored in slot at position, zero is returned for slot after end of file
+func (service *service) readVersion(pos int64) (int64, error) {
+	buffer := newSlot()
+	defer buffer.release()
+
+	version := buffer[:8]
+
+	if _, err := service.readAt(version, pos+versionOffset); err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return int64(binary.LittleEndian.Uint64(version)), nil
+}
+
+// writeEmptySlots method writes deleted slots from end of file to position of new record
+// empty slots are free for new records when slot reuse is enabled
+func (service *service) writeEmptySlots(endPos, recPos int64) error {
+	count := (recPos - endPos) / recordSize
+
+	if count > maxSlotGap {
+		return errors.Wrapf(record.ErrIDOutOfRange, "id %d is more than %d slots after end of file", slotID(recPos), maxSlotGap)
+	}
+
+	if count == 0 {
+		return nil
 	}
 
-	if err := binary.Write(file, binary.LittleEndian, rec.IntValue); err != nil {
+	emptySlot := newSlot()
+	defer emptySlot.release()
+
+	if err := encodeRecord(emptySlot[:], &record.Record{TimeValue: &time.Time{}}, 0); err != nil {
 		return errors.WithStack(err)
 	}
 
-	strBytes := make([]byte, 64)
-	copy(strBytes, rec.StrValue)
+	slots := bytes.Repeat(emptySlot[:], int(count))
 
-	if _, err := file.Write(strBytes); err != nil {
+	if err := service.writeAt(endPos, slots); err != nil {
 		return errors.WithStack(err)
 	}
 
-	boolByte := byte(0)
-	if rec.BoolValue {
-		boolByte = byte(1)
+	// empty slots are reusable at once, they are placed before slots of deleted records
+	if service.reuseSlots {
+		emptySlots := make([]int64, 0, count)
+
+		for pos := endPos; pos < recPos; pos += recordSize {
+			emptySlots = append(emptySlots, slotID(pos))
+		}
+
+		service.freeSlots = append(emptySlots, service.freeSlots...)
 	}
 
-	if _, err := file.Write([]byte{boolByte}); err != nil {
+	return nil
+}
+
+// checkRecord method verifies that record can be stored
+func (service *service) checkRecord(rec *record.Record) error {
+	if len(rec.StrValue) > service.maxStrLength {
+		return errors.Wrapf(record.ErrStrValueTooLong, "length %d bytes exceeds limit %d bytes", len(rec.StrValue), service.maxStrLength)
+	}
+
+	if rec.TimeValue == nil {
+		return errors.New("TimeValue is missing")
+	}
+
+	return nil
+}
+
+// writeRecord method writes record into storage file at position
+// record is encoded into pooled slot buffer and written by one write through write-ahead log
+// long StrValue is appended to heap file before record is written
+func (service *service) writeRecord(pos int64, rec *record.Record) error {
+	if err := service.checkRecord(rec); err != nil {
 		return errors.WithStack(err)
 	}
 
-	timeBytes, err := rec.TimeValue.MarshalBinary()
+	var heapPos int64
 
+	if len(rec.StrValue) > inlineStrLength {
+		var err error
+
+		if heapPos, err = service.appendHeap(rec.StrValue); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	version, err := service.readVersion(pos)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	timeLen := len(timeBytes)
+	rec.Version = version + 1
 
-	if timeLen < 16 {
-		padding := make([]byte, 16-timeLen)
-		timeBytes = append(timeBytes, padding...)
+	buffer := newSlot()
+	defer buffer.release()
+
+	if err := encodeRecord(buffer[:], rec, heapPos); err != nil {
+		return errors.WithStack(err)
 	}
 
-	if _, err := file.Write(timeBytes); err != nil {
+	previous, err := service.readIndexedRecord(pos)
+	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	if _, err := file.Write([]byte{'\n'}); err != nil {
+	if service.historyFile != nil {
+		if err := service.appendHistory(pos, rec); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := service.writeAt(pos, buffer[:]); err != nil {
 		return errors.WithStack(err)
 	}
 
+	// record written with zero id is deleted
+	current := rec
+
+	if rec.Id == 0 {
+		current = nil
+	}
+
+	service.afterWrite(func() {
+		service.updateIndexes(slotID(pos), previous, current)
+		service.updateSearchIndex(slotID(pos), current)
+	})
+
 	log.Debugf("Record %+v", rec)
 	return nil
 }
@@ -1,8 +1,13 @@
 package storage
 
 import (
+	"errors"
 	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"math/rand"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,13 +21,10 @@ func TestGetRecord(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(tmpfile.Name())
+	testtools.CleanupFiles(t, tmpfile.Name())
 	defer tmpfile.Close()
 
-	service := &service{
-		storageFilePath: tmpfile.Name(),
-		storageFile:     tmpfile,
-	}
+	service := newTestService(t, tmpfile.Name())
 
 	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
 
@@ -54,13 +56,10 @@ func TestCreateRecords(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(tmpfile.Name())
+	testtools.CleanupFiles(t, tmpfile.Name())
 	defer tmpfile.Close()
 
-	service := &service{
-		storageFilePath: tmpfile.Name(),
-		storageFile:     tmpfile,
-	}
+	service := newTestService(t, tmpfile.Name())
 
 	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
 
@@ -98,13 +97,10 @@ func TestDeleteRecord(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(tmpfile.Name())
+	testtools.CleanupFiles(t, tmpfile.Name())
 	defer tmpfile.Close()
 
-	service := &service{
-		storageFilePath: tmpfile.Name(),
-		storageFile:     tmpfile,
-	}
+	service := newTestService(t, tmpfile.Name())
 
 	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
 
@@ -141,18 +137,195 @@ func TestDeleteRecord(t *testing.T) {
 	assert.Equal(t, true, deleted2)
 }
 
+func TestDeleteRecordNotFound(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "delete_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 3; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	deleted, err := service.DeleteRecord(2)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	// record with id 3 written into slot of record 1
+	writeStoredID(t, tmpfile, 1, 3)
+
+	tests := []struct {
+		name string
+		id   int64
+	}{{
+		name: "Zero id",
+		id:   0,
+	}, {
+		name: "Negative id",
+		id:   -1,
+	}, {
+		name: "Id after end of file",
+		id:   4,
+	}, {
+		name: "Deleted record",
+		id:   2,
+	}, {
+		name: "Slot with other record",
+		id:   1,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted, err := service.DeleteRecord(tt.id)
+			assert.NoError(t, err)
+			assert.False(t, deleted)
+		})
+	}
+
+	// misplaced record does not hide record stored in its own slot
+	deleted, err = service.DeleteRecord(3)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	misplacedRecord, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, misplacedRecord)
+}
+
+func TestCreateRecordAt(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "create_at_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithSlotReuse(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	err = service.CreateRecordAt(3, &record.Record{IntValue: 3, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	err = service.CreateRecordAt(3, &record.Record{IntValue: 33, StrValue: "foo", TimeValue: &testingTime})
+	assert.ErrorIs(t, err, record.ErrRecordExists)
+
+	err = service.CreateRecordAt(0, &record.Record{IntValue: 0, StrValue: "foo", TimeValue: &testingTime})
+	assert.ErrorIs(t, err, record.ErrIDOutOfRange)
+
+	err = service.CreateRecordAt(4+maxSlotGap+1, &record.Record{IntValue: 0, StrValue: "foo", TimeValue: &testingTime})
+	assert.ErrorIs(t, err, record.ErrIDOutOfRange)
+
+	rec, err := service.GetRecord(3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), rec.IntValue)
+
+	// skipped slots are deleted slots reused by new records
+	stats, err := service.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Stats{Records: 1, DeletedRecords: 2}, *stats)
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 1, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), createdID)
+}
+
+func TestUpdateRecord(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "update_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	updated, err := service.UpdateRecord(1, func(rec *record.Record) error {
+		rec.Id = 5
+		rec.IntValue++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated.Id)
+	assert.Equal(t, int64(43), updated.IntValue)
+
+	// record is not written when update fails
+	updateErr := errors.New("update failed")
+
+	_, err = service.UpdateRecord(1, func(rec *record.Record) error {
+		rec.IntValue = 0
+		return updateErr
+	})
+	assert.ErrorIs(t, err, updateErr)
+
+	rec, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(43), rec.IntValue)
+
+	notFound, err := service.UpdateRecord(2, func(rec *record.Record) error { return nil })
+	assert.NoError(t, err)
+	assert.Nil(t, notFound)
+}
+
+func TestRecordVersion(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "version_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithSlotReuse(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	rec := record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime}
+
+	_, err = service.CreateRecord(&rec)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rec.Version)
+
+	_, err = service.EditRecord(1, &record.Record{IntValue: 43, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	edited, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), edited.Version)
+
+	// record created in deleted slot continues with version of deleted record
+	_, err = service.DeleteRecord(1)
+	assert.NoError(t, err)
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 44, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), createdID)
+
+	created, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), created.Version)
+}
+
 func TestGetRecordNotFound(t *testing.T) {
 	tmpfile, err := os.CreateTemp("", "get_record.bin")
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(tmpfile.Name())
+	testtools.CleanupFiles(t, tmpfile.Name())
 	defer tmpfile.Close()
 
-	service := &service{
-		storageFilePath: tmpfile.Name(),
-		storageFile:     tmpfile,
-	}
+	service := newTestService(t, tmpfile.Name())
 
 	recordNotFound, err := service.GetRecord(42)
 	assert.NoError(t, err)
@@ -166,13 +339,10 @@ func TestEditRecord(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(tmpfile.Name())
+	testtools.CleanupFiles(t, tmpfile.Name())
 	defer tmpfile.Close()
 
-	service := &service{
-		storageFilePath: tmpfile.Name(),
-		storageFile:     tmpfile,
-	}
+	service := newTestService(t, tmpfile.Name())
 
 	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
 
@@ -212,3 +382,271 @@ func TestEditRecord(t *testing.T) {
 	assert.Equal(t, testingTimeForUpdate, *updatedRecord.TimeValue)
 	assert.Equal(t, int64(142), updatedRecord.IntValue)
 }
+
+func TestCreateRecordReusesDeletedSlot(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "reuse_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	tmpfile.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	tests := []struct {
+		name       string
+		reuseSlots bool
+		expectedID int64
+	}{{
+		name:       "Slot reuse disabled - record is appended",
+		reuseSlots: false,
+		expectedID: int64(4),
+	}, {
+		name:       "Slot reuse enabled - deleted slot is used",
+		reuseSlots: true,
+		expectedID: int64(2),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer os.Truncate(tmpfile.Name(), 0)
+
+			service, err := NewService(tmpfile.Name(), WithSlotReuse(tt.reuseSlots))
+			assert.NoError(t, err)
+
+			for i := 0; i < 3; i++ {
+				_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+				assert.NoError(t, err)
+			}
+
+			deleted, err := service.DeleteRecord(2)
+			assert.NoError(t, err)
+			assert.True(t, deleted)
+
+			// free slots are rebuilt from file after restart
+			service.Close()
+			service, err = NewService(tmpfile.Name(), WithSlotReuse(tt.reuseSlots))
+			assert.NoError(t, err)
+			defer service.Close()
+
+			createdID, err := service.CreateRecord(&record.Record{IntValue: 99, StrValue: "bar", TimeValue: &testingTime})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedID, createdID)
+
+			createdRecord, err := service.GetRecord(createdID)
+			assert.NoError(t, err)
+			assert.NotNil(t, createdRecord)
+			assert.Equal(t, "bar", createdRecord.StrValue)
+		})
+	}
+}
+
+func TestGetDeletedRecord(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "deleted_record.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	deleted, err := service.DeleteRecord(createdID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deletedRecord, err := service.GetRecord(createdID)
+	assert.NoError(t, err)
+	assert.Nil(t, deletedRecord)
+}
+
+// newTestService function opens storage service which is closed at the end of test
+func newTestService(t testing.TB, fileStoragePath string, options ...Option) *service {
+	storageService, err := NewService(fileStoragePath, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(storageService.Close)
+
+	return storageService.(*service)
+}
+
+func TestGetCorruptRecord(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "corrupt_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 3; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	_, err = service.DeleteRecord(3)
+	assert.NoError(t, err)
+
+	// flip bit in StrValue of second record
+	_, err = tmpfile.WriteAt([]byte{'g'}, slotPosition(2)+payloadOffset+8)
+	assert.NoError(t, err)
+
+	corruptRecord, err := service.GetRecord(2)
+	assert.Nil(t, corruptRecord)
+	assert.ErrorIs(t, err, record.ErrCorruptRecord)
+
+	validRecord, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, validRecord)
+
+	stats, err := service.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Stats{Records: 1, DeletedRecords: 1, CorruptRecords: 1}, *stats)
+}
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "concurrent_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithSyncPolicy(SyncNever, 0))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 100; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+
+	// writer changes StrValue together with IntValue, reader must never see half written record
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 1; i <= 500; i++ {
+			id := int64(i%100 + 1)
+			_, err := service.EditRecord(id, &record.Record{Id: id, IntValue: id, StrValue: "bar", TimeValue: &testingTime})
+			assert.NoError(t, err)
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 1; i <= 500; i++ {
+				id := int64(i%100 + 1)
+
+				rec, err := service.GetRecord(id)
+				if assert.NoError(t, err) && assert.NotNil(t, rec) {
+					assert.Equal(t, id, rec.IntValue)
+					assert.Contains(t, []string{"foo", "bar"}, rec.StrValue)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// benchmarkRecords number of records in storage file of benchmarks
+const benchmarkRecords = 10000
+
+// newBenchmarkService function opens storage service with records for benchmark
+func newBenchmarkService(b *testing.B) *service {
+	tmpfile, err := os.CreateTemp("", "benchmark_records.bin")
+	if err != nil {
+		b.Fatal(err)
+	}
+	tmpfile.Close()
+
+	testtools.CleanupFiles(b, tmpfile.Name())
+
+	service := newTestService(b, tmpfile.Name(), WithSyncPolicy(SyncNever, 0))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= benchmarkRecords; i++ {
+		if _, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+
+	return service
+}
+
+func BenchmarkGetRecord(b *testing.B) {
+	service := newBenchmarkService(b)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := service.GetRecord(int64(i%benchmarkRecords + 1)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetRecordParallel benchmark of reads from all cores, reads are not serialized by lock
+func BenchmarkGetRecordParallel(b *testing.B) {
+	service := newBenchmarkService(b)
+
+	b.RunParallel(func(pb *testing.PB) {
+		random := rand.New(rand.NewSource(rand.Int63()))
+
+		for pb.Next() {
+			if _, err := service.GetRecord(random.Int63n(benchmarkRecords) + 1); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkGetRecordParallelWithWrites benchmark of reads from all cores while every tenth operation is edit
+func BenchmarkGetRecordParallelWithWrites(b *testing.B) {
+	service := newBenchmarkService(b)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	var operations atomic.Int64
+
+	b.RunParallel(func(pb *testing.PB) {
+		random := rand.New(rand.NewSource(rand.Int63()))
+
+		for pb.Next() {
+			id := random.Int63n(benchmarkRecords) + 1
+
+			var err error
+
+			if operations.Add(1)%10 == 0 {
+				_, err = service.EditRecord(id, &record.Record{Id: id, IntValue: id, StrValue: "bar", TimeValue: &testingTime})
+			} else {
+				_, err = service.GetRecord(id)
+			}
+
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
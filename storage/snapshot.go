@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"encoding/json"
+	"interviewtest/record"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Snapshot is directory with copies of storage file, heap file, history file and idempotency file
+// manifest describes copied files, indexes are not copied, they are rebuilt when restored storage is opened
+const (
+	snapshotManifestName  = "snapshot.json"
+	snapshotPartialSuffix = ".partial"
+	restoreDirSuffix      = ".restore"
+	replacedDirSuffix     = ".replaced"
+)
+
+// SnapshotPath function returns path of snapshot created at given time in directory of snapshots
+func SnapshotPath(snapshotDir string, createdAt time.Time) string {
+	return filepath.Join(snapshotDir, "snapshot-"+createdAt.UTC().Format("20060102T150405.000Z"))
+}
+
+// Snapshot method copies files of storage into new directory, copy is consistent point in time of storage
+// writes wait until files are copied, records are read during snapshot
+// snapshot is written into temporary directory which is renamed to dir when all files are synced
+// ErrSnapshotExists is returned when dir exists
+func (service *service) Snapshot(dir string) (*record.Snapshot, error) {
+	service.writeMu.Lock()
+	defer service.writeMu.Unlock()
+
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	paths := []string{service.storageFilePath, service.heapFile.Name(), historyFilePath(service.storageFilePath), idempotencyFilePath(service.storageFilePath)}
+
+	snapshot, err := writeSnapshot(dir, service.storageFilePath, paths)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	log.Infof("Snapshot of storage written into %s", dir)
+	return snapshot, nil
+}
+
+// writeSnapshot function copies existing files of storage into snapshot directory and writes manifest
+func writeSnapshot(dir, fileStoragePath string, paths []string) (*record.Snapshot, error) {
+	if _, err := os.Stat(dir); err == nil {
+		return nil, errors.Wrapf(record.ErrSnapshotExists, "directory %s", dir)
+	} else if !os.IsNotExist(err) {
+		return nil, errors.WithStack(err)
+	}
+
+	partialDir := dir + snapshotPartialSuffix
+
+	// directory left by interrupted snapshot is replaced
+	if err := os.RemoveAll(partialDir); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := os.MkdirAll(partialDir, os.ModePerm); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	snapshot := &record.Snapshot{
+		Path:        dir,
+		CreatedAt:   time.Now().UTC(),
+		StorageFile: filepath.Base(fileStoragePath),
+		Files:       []record.SnapshotFile{},
+	}
+
+	err := func() error {
+		for _, path := range paths {
+			name := filepath.Base(path)
+
+			size, err := copySnapshotFile(path, filepath.Join(partialDir, name))
+
+			// history and idempotency files exist only when they are enabled
+			if os.IsNotExist(errors.Cause(err)) && path != fileStoragePath {
+				continue
+			} else if err != nil {
+				return errors.WithStack(err)
+			}
+
+			snapshot.Files = append(snapshot.Files, record.SnapshotFile{Name: name, Size: size})
+		}
+
+		if err := writeManifest(filepath.Join(partialDir, snapshotManifestName), snapshot); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := syncDir(partialDir); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := os.Rename(partialDir, dir); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return syncDir(filepath.Dir(dir))
+	}()
+
+	if err != nil {
+		os.RemoveAll(partialDir)
+		return nil, errors.WithStack(err)
+	}
+
+	return snapshot, nil
+}
+
+// RestoreSnapshot function replaces files of storage by files of snapshot
+// Function must not be used when storage file is opened by running service
+// write-ahead log, indexes and files not contained in snapshot are removed, indexes are rebuilt when storage is opened
+// current files are moved aside until all files of snapshot are in place, they are moved back when restore fails
+func RestoreSnapshot(dir, fileStoragePath string) (*record.Snapshot, error) {
+	snapshot, err := readManifest(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	restoreDir := fileStoragePath + restoreDirSuffix
+
+	if err := os.RemoveAll(restoreDir); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := os.MkdirAll(restoreDir, os.ModePerm); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer os.RemoveAll(restoreDir)
+
+	// files are copied next to storage file before the current files are replaced
+	targets := make([]string, 0, len(snapshot.Files))
+
+	for _, file := range snapshot.Files {
+		target := fileStoragePath + strings.TrimPrefix(file.Name, snapshot.StorageFile)
+
+		size, err := copySnapshotFile(filepath.Join(dir, file.Name), filepath.Join(restoreDir, filepath.Base(target)))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if size != file.Size {
+			return nil, errors.Errorf("file %s of snapshot has %d bytes, %d bytes expected", file.Name, size, file.Size)
+		}
+
+		targets = append(targets, target)
+	}
+
+	replacedDir := fileStoragePath + replacedDirSuffix
+
+	if err := os.RemoveAll(replacedDir); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := os.MkdirAll(replacedDir, os.ModePerm); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	replaced, err := moveStorageFiles(fileStoragePath, replacedDir)
+	if err != nil {
+		os.RemoveAll(replacedDir)
+		return nil, errors.WithStack(err)
+	}
+
+	for i, target := range targets {
+		if err := os.Rename(filepath.Join(restoreDir, filepath.Base(target)), target); err != nil {
+			removeFiles(targets[:i])
+
+			// current files are kept in directory of replaced files when they can not be moved back
+			if err := moveBack(replaced, replacedDir); err != nil {
+				log.Errorf("Files of storage %s are left in %s: %s", fileStoragePath, replacedDir, err)
+				return nil, errors.WithStack(err)
+			}
+
+			os.RemoveAll(replacedDir)
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if err := syncDir(filepath.Dir(fileStoragePath)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := os.RemoveAll(replacedDir); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	log.Infof("Storage file %s restored from snapshot %s created at %s", fileStoragePath, dir, snapshot.CreatedAt)
+	return snapshot, nil
+}
+
+// readManifest function reads manifest of snapshot and checks names of its files
+func readManifest(dir string) (*record.Snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, snapshotManifestName))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var snapshot record.Snapshot
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, errors.Wrapf(err, "manifest of snapshot %s", dir)
+	}
+
+	containsStorageFile := false
+
+	for _, file := range snapshot.Files {
+		if filepath.Base(file.Name) != file.Name || !strings.HasPrefix(file.Name, snapshot.StorageFile) {
+			return nil, errors.Errorf("snapshot %s contains invalid file name %q", dir, file.Name)
+		}
+
+		containsStorageFile = containsStorageFile || file.Name == snapshot.StorageFile
+	}
+
+	if snapshot.StorageFile == "" || !containsStorageFile {
+		return nil, errors.Errorf("snapshot %s does not contain storage file", dir)
+	}
+
+	snapshot.Path = dir
+
+	return &snapshot, nil
+}
+
+// writeManifest function writes manifest of snapshot
+func writeManifest(path string, snapshot *record.Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	manifestFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer manifestFile.Close()
+
+	if _, err := manifestFile.Write(data); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(manifestFile.Sync())
+}
+
+// moveStorageFiles function moves storage file and all files which belong to it into directory
+// paths of moved files are returned, files moved before failure are moved back
+func moveStorageFiles(fileStoragePath, dir string) ([]string, error) {
+	paths := []string{fileStoragePath, fileStoragePath + walFileSuffix, historyFilePath(fileStoragePath), idempotencyFilePath(fileStoragePath), compactionMarkerPath(fileStoragePath)}
+	paths = append(paths, compactedFilePaths(fileStoragePath)...)
+
+	for _, pattern := range []string{fileStoragePath + ".heap.*", fileStoragePath + ".idx.*"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	moved := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		err := os.Rename(path, filepath.Join(dir, filepath.Base(path)))
+
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			moveBack(moved, dir)
+			return nil, errors.WithStack(err)
+		}
+
+		moved = append(moved, path)
+	}
+
+	return moved, nil
+}
+
+// moveBack function moves files from directory back to their paths
+func moveBack(paths []string, dir string) error {
+	for _, path := range paths {
+		if err := os.Rename(filepath.Join(dir, filepath.Base(path)), path); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// copySnapshotFile function copies file and returns size of the copy
+func copySnapshotFile(sourcePath, targetPath string) (int64, error) {
+	if err := copyFile(sourcePath, targetPath); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	stat, err := os.Stat(targetPath)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return stat.Size(), nil
+}
+
+// syncDir function syncs directory, so renamed files are persisted
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer dir.Close()
+
+	return errors.WithStack(dir.Sync())
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	storageFilePath := filepath.Join(dir, "records.bin")
+	snapshotPath := SnapshotPath(filepath.Join(dir, "snapshots"), time.Now())
+
+	service := newTestService(t, storageFilePath, WithIndexes("IntValue"), WithHistory(true), WithIdempotencyTTL(time.Hour))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+	longStrValue := strings.Repeat("foo", 50)
+
+	_, err := service.CreateRecord(&record.Record{IntValue: 1, StrValue: longStrValue, TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.CreateRecordIdempotent("key", &record.Record{IntValue: 2, StrValue: "bar", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.EditRecord(2, &record.Record{IntValue: 3, StrValue: "bar", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	snapshot, err := service.Snapshot(snapshotPath)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshotPath, snapshot.Path)
+	assert.Equal(t, "records.bin", snapshot.StorageFile)
+
+	names := make([]string, 0, len(snapshot.Files))
+
+	for _, file := range snapshot.Files {
+		names = append(names, file.Name)
+	}
+
+	assert.Equal(t, []string{"records.bin", "records.bin.heap.0", "records.bin.history", "records.bin.idempotency"}, names)
+
+	// writes after snapshot are not in snapshot
+	_, err = service.CreateRecord(&record.Record{IntValue: 4, StrValue: "baz", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.DeleteRecord(1)
+	assert.NoError(t, err)
+
+	_, err = service.Snapshot(snapshotPath)
+	assert.ErrorIs(t, err, record.ErrSnapshotExists)
+
+	service.Close()
+
+	restored, err := RestoreSnapshot(snapshotPath, storageFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.Files, restored.Files)
+
+	for _, path := range []string{storageFilePath + restoreDirSuffix, storageFilePath + replacedDirSuffix} {
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	service = newTestService(t, storageFilePath, WithIndexes("IntValue"), WithHistory(true), WithIdempotencyTTL(time.Hour))
+
+	rec, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, longStrValue, rec.StrValue)
+
+	rec, err = service.GetRecord(3)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+
+	ids, err := service.LookupRange(intRange(1, 4))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	history, err := service.RecordHistory(2)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	created, err := service.CreateRecordIdempotent("key", &record.Record{IntValue: 2, StrValue: "bar", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.False(t, created)
+}
+
+func TestRestoreIncompleteSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	storageFilePath := filepath.Join(dir, "records.bin")
+	snapshotPath := filepath.Join(dir, "snapshot")
+
+	service := newTestService(t, storageFilePath)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	_, err := service.CreateRecord(&record.Record{IntValue: 1, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	_, err = service.Snapshot(snapshotPath)
+	assert.NoError(t, err)
+
+	service.Close()
+
+	// truncated copy of snapshot is not restored, storage file is kept
+	assert.NoError(t, os.Truncate(filepath.Join(snapshotPath, "records.bin"), headerSize))
+
+	_, err = RestoreSnapshot(snapshotPath, storageFilePath)
+	assert.Error(t, err)
+
+	stat, err := os.Stat(storageFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+recordSize), stat.Size())
+}
+
+func TestRestoreSnapshotFailureKeepsStorage(t *testing.T) {
+	dir := t.TempDir()
+	storageFilePath := filepath.Join(dir, "records.bin")
+	snapshotPath := filepath.Join(dir, "snapshot")
+
+	service := newTestService(t, storageFilePath)
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC)
+
+	_, err := service.CreateRecord(&record.Record{IntValue: 1, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	snapshot, err := service.Snapshot(snapshotPath)
+	assert.NoError(t, err)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 2, StrValue: "bar", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	service.Close()
+
+	// the last file of snapshot can not be renamed over directory
+	assert.NoError(t, os.WriteFile(filepath.Join(snapshotPath, "records.bin.extra"), []byte("extra"), os.ModePerm))
+	assert.NoError(t, os.MkdirAll(filepath.Join(storageFilePath+".extra", "dir"), os.ModePerm))
+
+	snapshot.Files = append(snapshot.Files, record.SnapshotFile{Name: "records.bin.extra", Size: 5})
+	assert.NoError(t, os.Remove(filepath.Join(snapshotPath, snapshotManifestName)))
+	assert.NoError(t, writeManifest(filepath.Join(snapshotPath, snapshotManifestName), snapshot))
+
+	_, err = RestoreSnapshot(snapshotPath, storageFilePath)
+	assert.Error(t, err)
+
+	_, err = os.Stat(storageFilePath + replacedDirSuffix)
+	assert.True(t, os.IsNotExist(err))
+
+	service = newTestService(t, storageFilePath)
+
+	rec, err := service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rec.IntValue)
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"encoding/binary"
+	"interviewtest/record"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Stats method scans whole file and counts live, deleted and corrupted records
+// corrupted records are counted separately, they are not included in live or deleted records
+func (service *service) Stats() (*record.Stats, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	var stats record.Stats
+
+	buffer := newSlot()
+	defer buffer.release()
+
+	reader := slotReader(service.storageFile, headerSize)
+	slot := buffer[:]
+
+	for {
+		if _, err := io.ReadFull(reader, slot); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		switch {
+		case !validChecksum(slot):
+			stats.CorruptRecords++
+		case !validHeapEntry(slot, service.heapFile):
+			stats.CorruptRecords++
+		case binary.LittleEndian.Uint64(slot) == 0:
+			stats.DeletedRecords++
+		default:
+			stats.Records++
+		}
+	}
+
+	return &stats, nil
+}
+
+// validHeapEntry function verifies heap entry of long StrValue stored in slot
+func validHeapEntry(slot []byte, heapFile *os.File) bool {
+	strLength := binary.LittleEndian.Uint32(slot[strLengthOffset:])
+
+	if strLength <= inlineStrLength {
+		return true
+	}
+
+	_, err := readHeap(heapFile, int64(binary.LittleEndian.Uint64(slot[strValueOffset:])), strLength)
+
+	return err == nil
+}
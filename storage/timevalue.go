@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TimeValue is stored in 16 bytes of record slot
+// unix seconds(8) | nanoseconds(4) | zone offset in seconds(4)
+// name of time zone is not stored, time is restored in UTC, in local time zone when offset
+// matches offset of local time zone at that instant, otherwise in fixed zone with stored offset
+const (
+	timeValueSize = 16
+	// offset which is not valid for any time zone marks time in UTC
+	utcZoneOffset = math.MinInt32
+)
+
+// encodeTime function encodes time into bytes of TimeValue field
+func encodeTime(buffer []byte, t *time.Time) error {
+	if t == nil {
+		return errors.New("TimeValue is missing")
+	}
+
+	offset := int32(utcZoneOffset)
+
+	if t.Location() != time.UTC {
+		_, zoneOffset := t.Zone()
+		offset = int32(zoneOffset)
+	}
+
+	binary.LittleEndian.PutUint64(buffer, uint64(t.Unix()))
+	binary.LittleEndian.PutUint32(buffer[8:], uint32(t.Nanosecond()))
+	binary.LittleEndian.PutUint32(buffer[12:], uint32(offset))
+
+	return nil
+}
+
+// decodeTime function decodes time from bytes of TimeValue field
+func decodeTime(buffer []byte) (time.Time, error) {
+	seconds := int64(binary.LittleEndian.Uint64(buffer))
+	nanoseconds := binary.LittleEndian.Uint32(buffer[8:])
+	offset := int32(binary.LittleEndian.Uint32(buffer[12:]))
+
+	if nanoseconds >= uint32(time.Second) {
+		return time.Time{}, errors.Errorf("invalid nanoseconds %d of TimeValue", nanoseconds)
+	}
+
+	t := time.Unix(seconds, int64(nanoseconds))
+
+	if offset == utcZoneOffset {
+		return t.UTC(), nil
+	}
+
+	if _, localOffset := t.In(time.Local).Zone(); localOffset == int(offset) {
+		return t.In(time.Local), nil
+	}
+
+	return t.In(time.FixedZone("", int(offset))), nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeValueRoundTrip(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "time_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	tests := []struct {
+		name      string
+		timeValue time.Time
+	}{{
+		name:      "Local time",
+		timeValue: time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local),
+	}, {
+		name:      "UTC time",
+		timeValue: time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.UTC),
+	}, {
+		name:      "Time in zone with zero offset",
+		timeValue: time.Date(2023, 12, 31, 12, 42, 59, 0, time.FixedZone("", 0)),
+	}, {
+		name:      "Time in zone with negative offset",
+		timeValue: time.Date(2023, 6, 1, 0, 0, 0, 1, time.FixedZone("", -(3*3600+30*60))),
+	}, {
+		name:      "Time in zone with offset in seconds",
+		timeValue: time.Date(1890, 1, 1, 0, 0, 0, 0, time.FixedZone("", 3464)),
+	}, {
+		name:      "Time before unix epoch",
+		timeValue: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, {
+		name:      "Time far in future",
+		timeValue: time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.FixedZone("", 14*3600)),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdID, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &tt.timeValue})
+			assert.NoError(t, err)
+
+			rec, err := service.GetRecord(createdID)
+			assert.NoError(t, err)
+			assert.NotNil(t, rec)
+			assert.True(t, tt.timeValue.Equal(*rec.TimeValue))
+			assert.Equal(t, tt.timeValue.Format(time.RFC3339Nano), rec.TimeValue.Format(time.RFC3339Nano))
+
+			// local and UTC time is restored with the same location
+			if tt.timeValue.Location() == time.Local || tt.timeValue.Location() == time.UTC {
+				assert.Equal(t, tt.timeValue, *rec.TimeValue)
+			}
+		})
+	}
+}
+
+func TestDecodeTimeInvalidNanoseconds(t *testing.T) {
+	timeBytes := make([]byte, timeValueSize)
+	timeBytes[11] = 0xFF
+
+	_, err := decodeTime(timeBytes)
+	assert.Error(t, err)
+}
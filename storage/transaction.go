@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// transaction with writes staged in memory until commit
+// staged slots are visible only to writes of transaction, storage file is not changed before commit
+// committed writes are logged as one entry of write-ahead log, so transaction survives crash completely or not at all
+// other writes of storage wait until transaction is committed or rolled back, readers are not blocked
+type transaction struct {
+	service   *service
+	done      bool
+	slots     map[int64][]byte
+	positions []int64
+	fileSize  int64
+	size      int64
+	history   []historyChange
+	changes   []func()
+	freeSlots []int64
+}
+
+// historyChange previous version of record appended to history file when transaction is committed
+type historyChange struct {
+	id       int64
+	version  int64
+	previous *record.Record
+}
+
+// Begin method starts transaction, other writes wait until transaction is committed or rolled back
+// owner of transaction must not call write methods of storage before transaction is finished
+func (service *service) Begin() (record.Transaction, error) {
+	service.lockWrite()
+	defer service.mu.Unlock()
+
+	if err := service.begin(); err != nil {
+		service.writeMu.Unlock()
+		return nil, errors.WithStack(err)
+	}
+
+	return service.tx, nil
+}
+
+// begin method creates open transaction, caller holds the lock
+func (service *service) begin() error {
+	stat, err := service.storageFile.Stat()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.tx = &transaction{
+		service:   service,
+		slots:     make(map[int64][]byte),
+		fileSize:  stat.Size(),
+		size:      stat.Size(),
+		freeSlots: append([]int64(nil), service.freeSlots...),
+	}
+
+	return nil
+}
+
+// rollback method discards staged writes of transaction
+// heap entries of discarded records are not referenced, they are removed by compaction
+func (service *service) rollback() {
+	service.freeSlots = service.tx.freeSlots
+	service.tx = nil
+}
+
+// commit method writes staged slots of transaction, applies changes of indexes and appends history entries
+// history entries are appended after staged slots are logged, so rolled back or failed commit leaves no history
+func (service *service) commit() error {
+	tx := service.tx
+	service.tx = nil
+
+	if len(tx.positions) > 0 {
+		sort.Slice(tx.positions, func(i, j int) bool { return tx.positions[i] < tx.positions[j] })
+
+		writes := make([]walWrite, 0, len(tx.positions))
+
+		for _, pos := range tx.positions {
+			writes = append(writes, walWrite{pos: pos, data: tx.slots[pos]})
+		}
+
+		if err := service.writeAll(writes); err != nil {
+			service.freeSlots = tx.freeSlots
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, change := range tx.changes {
+		change()
+	}
+
+	// transaction is committed, missing history entry does not revert its writes
+	for _, change := range tx.history {
+		if err := service.appendHistoryEntry(change.id, change.version, change.previous); err != nil {
+			log.Errorf("History of record %d changed by transaction not appended: %s", change.id, err)
+		}
+	}
+
+	return nil
+}
+
+// stage method copies written data into staged slots, slot is read from storage file when it is staged first time
+func (tx *transaction) stage(storageFile *os.File, pos int64, data []byte) error {
+	for len(data) > 0 {
+		slotPos := pos - (pos-headerSize)%recordSize
+
+		slot, ok := tx.slots[slotPos]
+
+		if !ok {
+			slot = make([]byte, recordSize)
+
+			if slotPos < tx.fileSize {
+				if _, err := storageFile.ReadAt(slot, slotPos); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			tx.slots[slotPos] = slot
+			tx.positions = append(tx.positions, slotPos)
+		}
+
+		written := copy(slot[pos-slotPos:], data)
+		data = data[written:]
+		pos += int64(written)
+
+		if slotPos+recordSize > tx.size {
+			tx.size = slotPos + recordSize
+		}
+	}
+
+	return nil
+}
+
+// readAt method reads data at position as it is seen by writes, staged slots of open transaction are read first
+// read must not cross boundary of slot
+func (service *service) readAt(data []byte, pos int64) (int, error) {
+	tx := service.tx
+
+	if tx == nil {
+		return service.storageFile.ReadAt(data, pos)
+	}
+
+	if pos >= tx.size {
+		return 0, io.EOF
+	}
+
+	slotPos := pos - (pos-headerSize)%recordSize
+
+	if slot, ok := tx.slots[slotPos]; ok {
+		return copy(data, slot[pos-slotPos:]), nil
+	}
+
+	return service.storageFile.ReadAt(data, pos)
+}
+
+// storageSize method returns size of storage file as it is seen by writes, staged slots of open transaction are included
+func (service *service) storageSize() (int64, error) {
+	if service.tx != nil {
+		return service.tx.size, nil
+	}
+
+	stat, err := service.storageFile.Stat()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return stat.Size(), nil
+}
+
+// afterWrite method applies change of in-memory state after write, change of open transaction is applied by commit
+func (service *service) afterWrite(change func()) {
+	if service.tx != nil {
+		service.tx.changes = append(service.tx.changes, change)
+		return
+	}
+
+	change()
+}
+
+// ApplyBatch method applies operations in one transaction, either all operations are applied or none
+// records of create and update operations get id and version of written record
+// BatchError with index of failed operation is returned, ErrRecordNotFound for update or delete of missing record
+func (service *service) ApplyBatch(operations []record.Operation) error {
+	tx, err := service.Begin()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for i := range operations {
+		if err := applyOperation(tx, &operations[i]); err != nil {
+			tx.Rollback()
+			return &record.BatchError{Index: i, Err: err}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// applyOperation function applies one operation of batch in transaction
+func applyOperation(tx record.Transaction, operation *record.Operation) error {
+	switch operation.Type {
+	case record.OperationCreate:
+		if operation.ID != 0 {
+			return tx.CreateRecordAt(operation.ID, operation.Record)
+		}
+
+		_, err := tx.CreateRecord(operation.Record)
+		return err
+	case record.OperationUpdate:
+		rec := operation.Record
+
+		updated, err := tx.UpdateRecord(operation.ID, func(stored *record.Record) error {
+			if operation.Condition != nil {
+				if err := operation.Condition(stored.Version); err != nil {
+					return err
+				}
+			}
+
+			*stored = *rec
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if updated == nil {
+			return errors.Wrapf(record.ErrRecordNotFound, "record %d", operation.ID)
+		}
+
+		rec.Id = updated.Id
+		rec.Version = updated.Version
+
+		return nil
+	case record.OperationDelete:
+		deleted, err := tx.DeleteRecordIf(operation.ID, operation.Condition)
+
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return errors.Wrapf(record.ErrRecordNotFound, "record %d", operation.ID)
+		}
+
+		return nil
+	}
+
+	return errors.Errorf("unknown operation %q", operation.Type)
+}
+
+// lock method locks service for operation of transaction, ErrTransactionDone is returned for finished transaction
+func (tx *transaction) lock() error {
+	tx.service.mu.Lock()
+
+	if tx.done {
+		tx.service.mu.Unlock()
+		return errors.WithStack(record.ErrTransactionDone)
+	}
+
+	return nil
+}
+
+// GetRecord method reads record with writes of transaction
+func (tx *transaction) GetRecord(id int64) (*record.Record, error) {
+	if err := tx.lock(); err != nil {
+		return nil, err
+	}
+	defer tx.service.mu.Unlock()
+
+	return tx.service.readRecord(tx.service.readAt, id)
+}
+
+// CreateRecord method creates record in transaction, id of record is assigned when record is staged
+func (tx *transaction) CreateRecord(rec *record.Record) (int64, error) {
+	if err := tx.lock(); err != nil {
+		return 0, err
+	}
+	defer tx.service.mu.Unlock()
+
+	return tx.service.createRecord(rec)
+}
+
+// CreateRecordAt method creates record with given id in transaction
+func (tx *transaction) CreateRecordAt(id int64, rec *record.Record) error {
+	if err := tx.lock(); err != nil {
+		return err
+	}
+	defer tx.service.mu.Unlock()
+
+	return tx.service.createRecordAt(id, rec)
+}
+
+// EditRecord method replaces existing record in transaction, zero id is returned when record does not exist
+func (tx *transaction) EditRecord(id int64, updatedRecord *record.Record) (int64, error) {
+	if err := tx.lock(); err != nil {
+		return 0, err
+	}
+	defer tx.service.mu.Unlock()
+
+	return tx.service.editRecord(id, updatedRecord)
+}
+
+// UpdateRecord method reads record, changes it by update function and writes it in transaction
+// update function must not call methods of storage or transaction
+func (tx *transaction) UpdateRecord(id int64, update func(rec *record.Record) error) (*record.Record, error) {
+	if err := tx.lock(); err != nil {
+		return nil, err
+	}
+	defer tx.service.mu.Unlock()
+
+	return tx.service.updateRecord(id, update)
+}
+
+// DeleteRecord method deletes record in transaction
+func (tx *transaction) DeleteRecord(id int64) (bool, error) {
+	return tx.DeleteRecordIf(id, nil)
+}
+
+// DeleteRecordIf method deletes record in transaction when condition on version of record succeeds
+func (tx *transaction) DeleteRecordIf(id int64, condition func(version int64) error) (bool, error) {
+	if err := tx.lock(); err != nil {
+		return false, err
+	}
+	defer tx.service.mu.Unlock()
+
+	return tx.service.deleteRecordIf(id, condition)
+}
+
+// Commit method writes all writes of transaction through one journal entry and makes them visible to readers
+// transaction is finished also when commit fails, its writes are not visible to readers then
+// error wrapping ErrIndeterminateWrite is returned when commit failed after its journal entry was written,
+// writes can be partially written to storage file and journal entry can be replayed after crash,
+// storage file is not changed when commit fails with other error
+func (tx *transaction) Commit() error {
+	if err := tx.lock(); err != nil {
+		return err
+	}
+	defer tx.service.writeMu.Unlock()
+	defer tx.service.mu.Unlock()
+
+	tx.done = true
+
+	return tx.service.commit()
+}
+
+// Rollback method discards writes of transaction, rollback of finished transaction does nothing
+func (tx *transaction) Rollback() {
+	if err := tx.lock(); err != nil {
+		return
+	}
+	defer tx.service.writeMu.Unlock()
+	defer tx.service.mu.Unlock()
+
+	tx.done = true
+	tx.service.rollback()
+}
@@ -0,0 +1,346 @@
+package storage
+
+import (
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyBatch(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "batch_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"), WithHistory(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 2; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	created := &record.Record{IntValue: 30, StrValue: "bar", TimeValue: &testingTime}
+	createdAt := &record.Record{IntValue: 31, StrValue: "baz", TimeValue: &testingTime}
+	updated := &record.Record{IntValue: 32, StrValue: "foo", TimeValue: &testingTime}
+
+	err = service.ApplyBatch([]record.Operation{
+		{Type: record.OperationCreate, Record: created},
+		{Type: record.OperationCreate, ID: 6, Record: createdAt},
+		{Type: record.OperationUpdate, ID: 1, Record: updated},
+		{Type: record.OperationDelete, ID: 2},
+		// record created by batch is changed by the same batch
+		{Type: record.OperationUpdate, ID: 3, Record: &record.Record{IntValue: 33, StrValue: "bar", TimeValue: &testingTime}},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(3), created.Id)
+	assert.Equal(t, int64(6), createdAt.Id)
+	assert.Equal(t, int64(1), updated.Id)
+	assert.Equal(t, int64(2), updated.Version)
+
+	rec, err := service.GetRecord(3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(33), rec.IntValue)
+	assert.Equal(t, int64(2), rec.Version)
+
+	rec, err = service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+
+	ids, err := service.LookupRange(intRange(30, 40))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 3, 6}, ids)
+
+	history, err := service.RecordHistory(3)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestApplyBatchRollback(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "batch_rollback_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"), WithHistory(true), WithSlotReuse(true))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 2; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: int64(i), StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	stat, err := tmpfile.Stat()
+	assert.NoError(t, err)
+
+	err = service.ApplyBatch([]record.Operation{
+		{Type: record.OperationCreate, Record: &record.Record{IntValue: 30, StrValue: "bar", TimeValue: &testingTime}},
+		{Type: record.OperationUpdate, ID: 1, Record: &record.Record{IntValue: 31, StrValue: "foo", TimeValue: &testingTime}},
+		{Type: record.OperationDelete, ID: 2},
+		{Type: record.OperationUpdate, ID: 9, Record: &record.Record{IntValue: 32, StrValue: "foo", TimeValue: &testingTime}},
+	})
+
+	var batchError *record.BatchError
+
+	assert.True(t, errors.As(err, &batchError))
+	assert.Equal(t, 3, batchError.Index)
+	assert.ErrorIs(t, err, record.ErrRecordNotFound)
+
+	// nothing of failed batch is persisted
+	rolledBackStat, err := tmpfile.Stat()
+	assert.NoError(t, err)
+	assert.Equal(t, stat.Size(), rolledBackStat.Size())
+
+	rec, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rec.IntValue)
+	assert.Equal(t, int64(1), rec.Version)
+
+	rec, err = service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.NotNil(t, rec)
+
+	ids, err := service.LookupRange(intRange(30, 40))
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	history, err := service.RecordHistory(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	// slot of record deleted by failed batch is not free
+	assert.Empty(t, service.freeSlots)
+
+	// condition of operation is checked against version of stored record
+	err = service.ApplyBatch([]record.Operation{
+		{Type: record.OperationDelete, ID: 1, Condition: func(version int64) error {
+			return errors.Errorf("version %d does not match", version)
+		}},
+	})
+
+	assert.True(t, errors.As(err, &batchError))
+	assert.Equal(t, 0, batchError.Index)
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 33, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), createdID)
+}
+
+func TestTransaction(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "transaction_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name(), WithIndexes("IntValue"))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	for i := 1; i <= 2; i++ {
+		_, err := service.CreateRecord(&record.Record{IntValue: 10, StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	tx, err := service.Begin()
+	assert.NoError(t, err)
+
+	// value is moved between records
+	for id, delta := range map[int64]int64{1: -5, 2: 5} {
+		_, err := tx.UpdateRecord(id, func(rec *record.Record) error {
+			rec.IntValue += delta
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+
+	createdID, err := tx.CreateRecord(&record.Record{IntValue: 20, StrValue: "bar", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), createdID)
+
+	// transaction reads its writes, readers of storage do not see them
+	rec, err := tx.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), rec.IntValue)
+
+	rec, err = service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), rec.IntValue)
+
+	rec, err = service.GetRecord(createdID)
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+
+	ids, err := service.LookupRange(intRange(15, 20))
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// other write waits until transaction is finished
+	written := make(chan int64)
+
+	go func() {
+		id, err := service.CreateRecord(&record.Record{IntValue: 30, StrValue: "baz", TimeValue: &testingTime})
+		assert.NoError(t, err)
+		written <- id
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("write is not blocked by open transaction")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, int64(4), <-written)
+
+	for id, expected := range map[int64]int64{1: 5, 2: 15, 3: 20} {
+		rec, err := service.GetRecord(id)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rec.IntValue)
+	}
+
+	ids, err = service.LookupRange(intRange(15, 20))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, ids)
+
+	// finished transaction can not be used
+	_, err = tx.DeleteRecord(1)
+	assert.ErrorIs(t, err, record.ErrTransactionDone)
+	assert.ErrorIs(t, tx.Commit(), record.ErrTransactionDone)
+	tx.Rollback()
+}
+
+func TestTransactionRollback(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "transaction_rollback_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 10, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	tx, err := service.Begin()
+	assert.NoError(t, err)
+
+	_, err = tx.EditRecord(1, &record.Record{IntValue: 11, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	assert.NoError(t, tx.CreateRecordAt(5, &record.Record{IntValue: 12, StrValue: "bar", TimeValue: &testingTime}))
+
+	tx.Rollback()
+
+	rec, err := service.GetRecord(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), rec.IntValue)
+
+	stat, err := tmpfile.Stat()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+recordSize), stat.Size())
+
+	// storage is writable after rollback
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 13, StrValue: "baz", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), createdID)
+}
+
+func TestTransactionCommitFailure(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "transaction_failure_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	service := newTestService(t, tmpfile.Name())
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	tx, err := service.Begin()
+	assert.NoError(t, err)
+
+	_, err = tx.CreateRecord(&record.Record{IntValue: 10, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	// write of storage file fails after journal entry is written
+	storageFile := service.storageFile
+	readOnlyFile, err := os.Open(tmpfile.Name())
+	assert.NoError(t, err)
+
+	service.storageFile = readOnlyFile
+	err = tx.Commit()
+	service.storageFile = storageFile
+	readOnlyFile.Close()
+
+	var pathError *os.PathError
+
+	assert.ErrorIs(t, err, record.ErrIndeterminateWrite)
+	assert.ErrorAs(t, err, &pathError)
+
+	walFile, err := os.Open(tmpfile.Name() + walFileSuffix)
+	assert.NoError(t, err)
+	defer walFile.Close()
+
+	pos, _, err := readWalEntry(walFile)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize), pos)
+}
+
+func TestTransactionJournal(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "transaction_journal_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	// write-ahead log is kept until periodic sync
+	service := newTestService(t, tmpfile.Name(), WithSyncPolicy(SyncInterval, time.Hour))
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	tx, err := service.Begin()
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := tx.CreateRecord(&record.Record{IntValue: 10, StrValue: "foo", TimeValue: &testingTime})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, tx.Commit())
+
+	walFile, err := os.Open(tmpfile.Name() + walFileSuffix)
+	assert.NoError(t, err)
+	defer walFile.Close()
+
+	// all writes of transaction are logged by one entry
+	pos, data, err := readWalEntry(walFile)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(walBatchPosition), pos)
+
+	writes, err := decodeWalBatch(data)
+	assert.NoError(t, err)
+	assert.Len(t, writes, 3)
+
+	_, _, err = readWalEntry(walFile)
+	assert.ErrorIs(t, err, io.EOF)
+}
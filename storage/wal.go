@@ -0,0 +1,353 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"interviewtest/record"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Entry of write-ahead log is crc32c(4) | position(8) | length(4) | data
+// writes of transaction are logged as one batch entry with position -1, its data are position(8) | length(4) | data of every write
+// batch entry is replayed completely or not at all
+const (
+	walFileSuffix = ".wal"
+	// crc32c(4) + position(8) + length(4)
+	walEntryHeaderSize = 16
+	walMaxEntrySize    = 1 << 20
+	walBatchPosition   = -1
+	// position(8) + length(4)
+	walWriteHeaderSize = 12
+	walMaxBatchSize    = 64 << 20
+)
+
+// walWrite write of data into storage file at position
+type walWrite struct {
+	pos  int64
+	data []byte
+}
+
+// SyncPolicy defines when written data are flushed to disk
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes write-ahead log and storage file after every write
+	SyncAlways SyncPolicy = iota
+	// SyncInterval flushes storage file periodically, data written in the last interval can be lost
+	// write-ahead log is not flushed before storage file is written, so it does not guarantee recovery of the last
+	// interval, slot damaged by crash is detected by its checksum and reported as corrupted record
+	SyncInterval
+	// SyncNever leaves flushing to operating system, only crash of process is survived
+	SyncNever
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ParseSyncPolicy function converts name of sync policy (always, interval, never) to SyncPolicy
+func ParseSyncPolicy(name string) (SyncPolicy, error) {
+	switch strings.ToLower(name) {
+	case "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never":
+		return SyncNever, nil
+	}
+
+	return SyncAlways, errors.Errorf("unknown sync policy %q", name)
+}
+
+// openWal method opens write-ahead log of storage file and recovers not applied writes
+func (service *service) openWal() error {
+	walFile, err := os.OpenFile(service.storageFilePath+walFileSuffix, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.walFile = walFile
+
+	return service.recover()
+}
+
+// recover method replays complete entries of write-ahead log into storage file
+// incomplete entries are discarded
+func (service *service) recover() error {
+	if _, err := service.walFile.Seek(0, io.SeekStart); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var replayed int
+
+	for {
+		pos, data, err := readWalEntry(service.walFile)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Warnf("Incomplete write-ahead log entry discarded: %s", err)
+			break
+		}
+
+		writes := []walWrite{{pos: pos, data: data}}
+
+		if pos == walBatchPosition {
+			if writes, err = decodeWalBatch(data); err != nil {
+				log.Warnf("Invalid write-ahead log batch discarded: %s", err)
+				break
+			}
+		}
+
+		for _, write := range writes {
+			if _, err := service.storageFile.WriteAt(write.data, write.pos); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		replayed += len(writes)
+	}
+
+	if replayed > 0 {
+		log.Infof("Write-ahead log recovered, %d writes replayed", replayed)
+	}
+
+	return service.checkpoint(true)
+}
+
+// writeAt method writes data into storage file at position through write-ahead log
+// write of open transaction is staged until transaction is committed
+func (service *service) writeAt(pos int64, data []byte) error {
+	if service.tx != nil {
+		return service.tx.stage(service.storageFile, pos, data)
+	}
+
+	return service.writeAll([]walWrite{{pos: pos, data: data}})
+}
+
+// writeAll method writes data into storage file through one entry of write-ahead log
+// more writes are logged as batch entry, so they are recovered together
+// log entry is flushed before storage file is written only by SyncAlways, other policies replay entries
+// which reached the disk, storage file can contain writes whose log entry was lost
+// failure after log entry is written wraps ErrIndeterminateWrite, writes can be partially written to storage file
+// and log entry can be replayed by recovery after crash, storage file is not changed by other failures
+func (service *service) writeAll(writes []walWrite) error {
+	var entry []byte
+
+	if len(writes) == 1 {
+		entry = encodeWalEntry(writes[0].pos, writes[0].data)
+	} else {
+		batch, err := encodeWalBatch(writes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		entry = encodeWalEntry(walBatchPosition, batch)
+	}
+
+	if _, err := service.walFile.Write(entry); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if service.syncPolicy == SyncAlways {
+		if err := service.walFile.Sync(); err != nil {
+			return indeterminateWrite(err)
+		}
+	}
+
+	for _, write := range writes {
+		if _, err := service.storageFile.WriteAt(write.data, write.pos); err != nil {
+			return indeterminateWrite(err)
+		}
+	}
+
+	var err error
+
+	switch service.syncPolicy {
+	case SyncAlways:
+		err = service.checkpoint(true)
+	case SyncNever:
+		err = service.checkpoint(false)
+	default:
+		service.unsynced = true
+	}
+
+	if err != nil {
+		return indeterminateWrite(err)
+	}
+
+	return nil
+}
+
+// indeterminateWrite function wraps error of write which failed after its log entry was written
+func indeterminateWrite(err error) error {
+	return errors.WithStack(fmt.Errorf("%w: %w", record.ErrIndeterminateWrite, err))
+}
+
+// checkpoint method truncates write-ahead log, storage file is flushed before when sync is required
+func (service *service) checkpoint(sync bool) error {
+	if sync {
+		if err := service.storageFile.Sync(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := service.walFile.Truncate(0); err != nil {
+		return errors.WithStack(err)
+	}
+
+	service.unsynced = false
+	return nil
+}
+
+// startPeriodicSync method starts periodic sync for interval sync policy
+func (service *service) startPeriodicSync() {
+	if service.syncPolicy != SyncInterval {
+		return
+	}
+
+	service.stopSync = make(chan struct{})
+	service.syncStopped = make(chan struct{})
+
+	go service.syncPeriodically(service.syncInterval)
+}
+
+// syncPeriodically method flushes storage file in interval until periodic sync is stopped
+func (service *service) syncPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(service.syncStopped)
+
+	for {
+		select {
+		case <-service.stopSync:
+			return
+		case <-ticker.C:
+			service.mu.Lock()
+
+			if service.unsynced {
+				if err := service.checkpoint(true); err != nil {
+					log.Errorf("Sync of storage file failed: %s", err)
+				}
+			}
+
+			service.mu.Unlock()
+		}
+	}
+}
+
+// stopPeriodicSync method stops periodic sync and waits until it is finished
+// method must be called without holding lock of service
+func (service *service) stopPeriodicSync() {
+	if service.stopSync == nil {
+		return
+	}
+
+	close(service.stopSync)
+	<-service.syncStopped
+	service.stopSync = nil
+}
+
+// closeWal method flushes storage file and removes empty write-ahead log
+func (service *service) closeWal() {
+	if err := service.checkpoint(true); err != nil {
+		log.Errorf("Checkpoint of storage file failed: %s", err)
+		service.walFile.Close()
+		return
+	}
+
+	service.walFile.Close()
+	os.Remove(service.walFile.Name())
+}
+
+// readWalEntry function reads one entry of write-ahead log and verifies its checksum
+func readWalEntry(reader io.Reader) (int64, []byte, error) {
+	header := make([]byte, walEntryHeaderSize)
+
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+
+	pos := int64(binary.LittleEndian.Uint64(header[4:]))
+	length := binary.LittleEndian.Uint32(header[12:])
+
+	if length > walMaxBatchSize || (length > walMaxEntrySize && pos != walBatchPosition) {
+		return 0, nil, errors.Errorf("invalid entry length %d", length)
+	}
+
+	data := make([]byte, length)
+
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return 0, nil, errors.WithStack(io.ErrUnexpectedEOF)
+	}
+
+	crc := crc32.Update(crc32.Checksum(header[4:], castagnoliTable), castagnoliTable, data)
+
+	if crc != binary.LittleEndian.Uint32(header) {
+		return 0, nil, errors.New("checksum mismatch")
+	}
+
+	return pos, data, nil
+}
+
+// encodeWalEntry function encodes entry of write-ahead log with data written at position
+func encodeWalEntry(pos int64, data []byte) []byte {
+	entry := make([]byte, walEntryHeaderSize+len(data))
+	binary.LittleEndian.PutUint64(entry[4:], uint64(pos))
+	binary.LittleEndian.PutUint32(entry[12:], uint32(len(data)))
+	copy(entry[walEntryHeaderSize:], data)
+	binary.LittleEndian.PutUint32(entry, crc32.Checksum(entry[4:], castagnoliTable))
+
+	return entry
+}
+
+// encodeWalBatch function encodes writes into data of batch entry
+func encodeWalBatch(writes []walWrite) ([]byte, error) {
+	size := 0
+
+	for _, write := range writes {
+		size += walWriteHeaderSize + len(write.data)
+	}
+
+	if size > walMaxBatchSize {
+		return nil, errors.Errorf("writes of %d bytes exceed limit of batch %d bytes", size, walMaxBatchSize)
+	}
+
+	batch := make([]byte, 0, size)
+
+	for _, write := range writes {
+		batch = binary.LittleEndian.AppendUint64(batch, uint64(write.pos))
+		batch = binary.LittleEndian.AppendUint32(batch, uint32(len(write.data)))
+		batch = append(batch, write.data...)
+	}
+
+	return batch, nil
+}
+
+// decodeWalBatch function decodes writes from data of batch entry
+func decodeWalBatch(batch []byte) ([]walWrite, error) {
+	var writes []walWrite
+
+	for len(batch) > 0 {
+		if len(batch) < walWriteHeaderSize {
+			return nil, errors.New("incomplete write header")
+		}
+
+		pos := int64(binary.LittleEndian.Uint64(batch))
+		length := int(binary.LittleEndian.Uint32(batch[8:]))
+		batch = batch[walWriteHeaderSize:]
+
+		if length > len(batch) {
+			return nil, errors.Errorf("write of %d bytes exceeds batch", length)
+		}
+
+		writes = append(writes, walWrite{pos: pos, data: batch[:length]})
+		batch = batch[length:]
+	}
+
+	return writes, nil
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"interviewtest/record"
+	"interviewtest/tools/testtools"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyncPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected SyncPolicy
+		err      bool
+	}{{
+		name:     "Always",
+		input:    "always",
+		expected: SyncAlways,
+	}, {
+		name:     "Interval",
+		input:    "Interval",
+		expected: SyncInterval,
+	}, {
+		name:     "Never",
+		input:    "never",
+		expected: SyncNever,
+	}, {
+		name:  "Unknown policy",
+		input: "sometimes",
+		err:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseSyncPolicy(tt.input)
+
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestRecoverReplaysWal(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "wal_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	service := newTestService(t, tmpfile.Name())
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	slot := make([]byte, recordSize)
+	_, err = tmpfile.ReadAt(slot, slotPosition(1))
+	assert.NoError(t, err)
+
+	service.Close()
+
+	// simulate crash - record is logged but only partially written, incomplete entry follows
+	walFile, err := os.OpenFile(tmpfile.Name()+walFileSuffix, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondSlot := append([]byte{}, slot...)
+	binary.LittleEndian.PutUint64(secondSlot, uint64(createdID+1))
+
+	walFile.Write(walEntry(slotPosition(2), secondSlot))
+	walFile.Write(walEntry(slotPosition(3), secondSlot)[:walEntryHeaderSize+10])
+	walFile.Close()
+
+	tmpfile.WriteAt(secondSlot[:40], slotPosition(2))
+
+	service = newTestService(t, tmpfile.Name())
+
+	recoveredRecord, err := service.GetRecord(createdID + 1)
+	assert.NoError(t, err)
+	assert.NotNil(t, recoveredRecord)
+	assert.Equal(t, int64(42), recoveredRecord.IntValue)
+	assert.Equal(t, "foo", recoveredRecord.StrValue)
+
+	stat, err := os.Stat(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+2*recordSize), stat.Size())
+
+	walStat, err := os.Stat(walFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), walStat.Size())
+}
+
+func TestRecoverReplaysWalBatch(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "wal_batch_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	service := newTestService(t, tmpfile.Name())
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	slot := make([]byte, recordSize)
+	_, err = tmpfile.ReadAt(slot, slotPosition(1))
+	assert.NoError(t, err)
+
+	service.Close()
+
+	secondSlot := append([]byte{}, slot...)
+	binary.LittleEndian.PutUint64(secondSlot, 2)
+
+	thirdSlot := append([]byte{}, slot...)
+	binary.LittleEndian.PutUint64(thirdSlot, 3)
+
+	// simulate crash - batch is logged but not written, incomplete batch follows
+	walFile, err := os.OpenFile(tmpfile.Name()+walFileSuffix, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := encodeWalBatch([]walWrite{{pos: slotPosition(2), data: secondSlot}, {pos: slotPosition(3), data: thirdSlot}})
+	assert.NoError(t, err)
+
+	incompleteBatch, err := encodeWalBatch([]walWrite{{pos: slotPosition(4), data: secondSlot}, {pos: slotPosition(5), data: thirdSlot}})
+	assert.NoError(t, err)
+
+	walFile.Write(encodeWalEntry(walBatchPosition, batch))
+	walFile.Write(encodeWalEntry(walBatchPosition, incompleteBatch)[:walEntryHeaderSize+recordSize+20])
+	walFile.Close()
+
+	service = newTestService(t, tmpfile.Name())
+
+	for _, id := range []int64{2, 3} {
+		recoveredRecord, err := service.GetRecord(id)
+		assert.NoError(t, err)
+		assert.NotNil(t, recoveredRecord)
+		assert.Equal(t, int64(42), recoveredRecord.IntValue)
+	}
+
+	stat, err := os.Stat(tmpfile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+3*recordSize), stat.Size())
+}
+
+func TestRecoverDiscardsTornRecord(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "torn_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	service := newTestService(t, tmpfile.Name())
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	service.Close()
+
+	tmpfile.WriteAt([]byte{2, 0, 0, 0}, slotPosition(2))
+
+	service = newTestService(t, tmpfile.Name())
+
+	tornRecord, err := service.GetRecord(2)
+	assert.NoError(t, err)
+	assert.Nil(t, tornRecord)
+
+	createdID, err := service.CreateRecord(&record.Record{IntValue: 99, StrValue: "bar", TimeValue: &testingTime})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), createdID)
+}
+
+func TestIntervalSyncPolicy(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "interval_records.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testtools.CleanupFiles(t, tmpfile.Name())
+	defer tmpfile.Close()
+
+	testingTime := time.Date(2023, 12, 31, 12, 42, 59, 987654321, time.Local)
+
+	service := newTestService(t, tmpfile.Name(), WithSyncPolicy(SyncInterval, 10*time.Millisecond))
+
+	_, err = service.CreateRecord(&record.Record{IntValue: 42, StrValue: "foo", TimeValue: &testingTime})
+	assert.NoError(t, err)
+
+	walStat, err := os.Stat(tmpfile.Name() + walFileSuffix)
+	assert.NoError(t, err)
+	assert.NotZero(t, walStat.Size())
+
+	assert.Eventually(t, func() bool {
+		walStat, err := os.Stat(tmpfile.Name() + walFileSuffix)
+		return err == nil && walStat.Size() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	service.Close()
+
+	_, err = os.Stat(tmpfile.Name() + walFileSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// walEntry function encodes entry of write-ahead log for test
+func walEntry(pos int64, data []byte) []byte {
+	entry := make([]byte, walEntryHeaderSize+len(data))
+	binary.LittleEndian.PutUint64(entry[4:], uint64(pos))
+	binary.LittleEndian.PutUint32(entry[12:], uint32(len(data)))
+	copy(entry[walEntryHeaderSize:], data)
+	binary.LittleEndian.PutUint32(entry, crc32.Checksum(entry[4:], castagnoliTable))
+
+	return entry
+}
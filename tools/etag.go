@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPreconditionFailed error of request with If-Match header which does not match current version of record
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ETag function returns entity tag of record version
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// MatchETag function reports whether header value (If-Match or If-None-Match) matches version of record
+// value is '*' or comma separated list of entity tags, weak tags (W/"...") match only when weak comparison is used
+func MatchETag(header string, version int64, weak bool) bool {
+	etag := ETag(version)
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+
+			tag = tag[2:]
+		}
+
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckIfMatch function returns ErrPreconditionFailed when If-Match header is given and does not match version
+// empty header means request is not conditional
+func CheckIfMatch(header string, version int64) error {
+	if header == "" || MatchETag(header, version, false) {
+		return nil
+	}
+
+	return errors.Wrapf(ErrPreconditionFailed, "record version is %s", ETag(version))
+}
@@ -2,39 +2,114 @@ package tools
 
 import (
 	"encoding/json"
+	"fmt"
+	"interviewtest/record"
 	"log"
 	"net/http"
+	"strings"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 )
 
-// RecordNotFound general error message for non-existent record
-var RecordNotFound = errors.New("record not found")
+// RecordNotFound general error message for non-existent record, it is the same error as returned by storage
+var RecordNotFound = record.ErrRecordNotFound
 
 // ErrorResponse structure for error response
 type ErrorResponse struct {
-	ErrText string `json:"errText"`
+	ErrText string       `json:"errText"`
+	Fields  []FieldError `json:"fields,omitempty"`
 }
 
-// SetErrResponse function sets http status code and error text into response
-// for non-existent record return 404, in other cases return 500
+// FieldError structure for error of one field or parameter of request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors error of request with invalid fields or parameters
+type FieldErrors []FieldError
+
+// Error method returns text of all field errors
+func (fieldErrors FieldErrors) Error() string {
+	messages := make([]string, 0, len(fieldErrors))
+
+	for _, fieldError := range fieldErrors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldError.Field, fieldError.Message))
+	}
+
+	return "invalid fields: " + strings.Join(messages, ", ")
+}
+
+// FieldErrorsFromValidation function converts validator.ValidationErrors into field errors
+// fieldPrefix is added to name of every invalid field, e.g. for record nested in request
+func FieldErrorsFromValidation(err error, fieldPrefix string) FieldErrors {
+	var fieldErrors FieldErrors
+
+	for _, fieldError := range err.(validator.ValidationErrors) {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   fieldPrefix + fieldError.Field(),
+			Message: fmt.Sprintf("field failed on %s validation", fieldError.Tag()),
+		})
+	}
+
+	return fieldErrors
+}
+
+// SetErrResponse function sets http status code given by ErrStatusCode and error text into response
+// for corrupted record only corruption error text is returned
 func SetErrResponse(response http.ResponseWriter, err error) {
-	if err != nil && errors.Is(err, RecordNotFound) {
-		SetErrResponseWithStatusCode(response, err, http.StatusNotFound)
+	var fieldErrors FieldErrors
+
+	if err != nil && errors.As(err, &fieldErrors) {
+		SetErrResponseWithStatusCode(response, fieldErrors, http.StatusBadRequest)
+		return
+	}
+
+	if err != nil && errors.Is(err, record.ErrCorruptRecord) {
+		SetErrResponseWithStatusCode(response, record.ErrCorruptRecord, http.StatusInternalServerError)
 		return
 	}
 
-	SetErrResponseWithStatusCode(response, err, http.StatusInternalServerError)
+	SetErrResponseWithStatusCode(response, err, ErrStatusCode(err))
+}
+
+// ErrStatusCode function returns http status code of error
+// for non-existent record return 404, for too long StrValue, id out of range and invalid fields return 400,
+// for record created with id of existing record and for failed If-Match precondition return 412, in other cases return 500
+func ErrStatusCode(err error) int {
+	var fieldErrors FieldErrors
+
+	switch {
+	case err == nil:
+		return http.StatusInternalServerError
+	case errors.Is(err, RecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, record.ErrStrValueTooLong) || errors.Is(err, record.ErrIDOutOfRange) || errors.As(err, &fieldErrors):
+		return http.StatusBadRequest
+	case errors.Is(err, record.ErrRecordExists) || errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	}
+
+	return http.StatusInternalServerError
 }
 
 // SetErrResponseWithStatusCode function sets http status code and error text into response
-// function sets error text into response as json
+// function sets error text into response as json, field errors are listed separately
 func SetErrResponseWithStatusCode(response http.ResponseWriter, err interface{}, statusCode int) {
 	response.WriteHeader(statusCode)
 
 	if err != nil {
 		log.Printf("Err: %s", err.(error).Error())
 
-		_ = json.NewEncoder(response).Encode(ErrorResponse{ErrText: err.(error).Error()})
+		errResponse := ErrorResponse{ErrText: err.(error).Error()}
+
+		var fieldErrors FieldErrors
+
+		if errors.As(err.(error), &fieldErrors) {
+			errResponse.Fields = fieldErrors
+		}
+
+		_ = json.NewEncoder(response).Encode(errResponse)
 	}
 }
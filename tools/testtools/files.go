@@ -0,0 +1,24 @@
+package testtools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// CleanupFiles function removes file at path and all files with the path as prefix when test is finished
+// storage keeps heap, index, history, idempotency and write-ahead log files next to storage file
+// cleanup registered before storage is closed runs after storage is closed, so files written by close are removed too
+func CleanupFiles(tb testing.TB, path string) {
+	tb.Cleanup(func() {
+		matches, err := filepath.Glob(path + "*")
+		if err != nil {
+			tb.Error(err)
+			return
+		}
+
+		for _, match := range matches {
+			os.RemoveAll(match)
+		}
+	})
+}